@@ -1,10 +1,598 @@
 package config
 
-import "time"
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Preethi0716/Cache-Library/preethi/restapi/pkg/cache"
+	"github.com/Preethi0716/Cache-Library/preethi/restapi/pkg/ratelimit"
+)
 
 type CacheConfig struct {
-	RedisAddr        string
+	ListenAddr string
+	RedisAddr  string
+	// RedisTopology is single, sentinel or cluster. Sentinels and cluster
+	// nodes are reached through RedisAddrs instead of RedisAddr.
+	RedisTopology    string
+	RedisAddrs       []string
+	RedisMasterName  string
 	MemcachedServers []string
 	MaxLRUSize       int
 	DefaultTTL       time.Duration
+	// QueryTimeout bounds how long a listing waits for each backend.
+	QueryTimeout      time.Duration
+	EncryptionKeyFile string
+	// AuthFile holds the API keys and token secret; without it the API is open.
+	AuthFile          string
+	PeerSelf          string
+	Peers             []string
+	ReplicationRole   string
+	ReplicationLeader string
+	// Backends are named caches added next to the built-in ones.
+	Backends []BackendConfig
+	// OptionalBackends may be unreachable at startup; they are retried in
+	// the background instead of failing the start.
+	OptionalBackends []string
+	// Resilience is the circuit breaker and retry policy of the remote backends.
+	Resilience cache.ResiliencePolicy
+	// RateLimit bounds the requests of each client, counted in RateLimitStore:
+	// "local" for each instance on its own or "redis" across instances, in
+	// database RateLimitRedisDB of RateLimitRedisAddr, which defaults to
+	// RedisAddr. The caches use database 0.
+	RateLimit          ratelimit.Policy
+	RateLimitStore     string
+	RateLimitRedisAddr string
+	RateLimitRedisDB   int
+	// File is the config file the settings were read from, if any.
+	File string
+}
+
+// BackendConfig describes a named cache. Addr is the Redis address, the
+// memcached servers separated by semicolons, or the capacity of an lru.
+type BackendConfig struct {
+	Name string
+	Type string
+	Addr string
+}
+
+func Default() *CacheConfig {
+	return &CacheConfig{
+		ListenAddr:       ":8080",
+		RedisAddr:        "localhost:6379",
+		RedisTopology:    string(cache.RedisSingle),
+		MemcachedServers: []string{"localhost:11211"},
+		MaxLRUSize:       5,
+		DefaultTTL:       time.Minute,
+		QueryTimeout:     5 * time.Second,
+		Resilience:       cache.DefaultResiliencePolicy(),
+		RateLimitStore:   "local",
+		RateLimitRedisDB: 1,
+	}
+}
+
+// setting is one configuration value. Its key is used as is in config
+// files, upper cased with a CACHE_ prefix in the environment, and with
+// dashes instead of underscores as a command-line flag.
+type setting struct {
+	key   string
+	usage string
+	set   func(c *CacheConfig, value string) error
+}
+
+var settings = []setting{
+	{"listen_addr", "address the HTTP server listens on", func(c *CacheConfig, v string) error {
+		c.ListenAddr = v
+		return nil
+	}},
+	{"redis_addr", "host:port of the Redis server", func(c *CacheConfig, v string) error {
+		c.RedisAddr = v
+		return nil
+	}},
+	{"redis_topology", "single, or sentinel or cluster to reach Redis through redis_addrs", func(c *CacheConfig, v string) error {
+		c.RedisTopology = v
+		return nil
+	}},
+	{"redis_addrs", "comma separated host:port of the Redis sentinels or cluster nodes", func(c *CacheConfig, v string) error {
+		c.RedisAddrs = splitList(v)
+		return nil
+	}},
+	{"redis_master_name", "name of the Redis master monitored by the sentinels", func(c *CacheConfig, v string) error {
+		c.RedisMasterName = v
+		return nil
+	}},
+	{"memcached_servers", "comma separated memcached servers as host:port[=weight]", func(c *CacheConfig, v string) error {
+		c.MemcachedServers = splitList(v)
+		return nil
+	}},
+	{"max_lru_size", "capacity of the in-memory caches", func(c *CacheConfig, v string) error {
+		size, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("%q is not an integer", v)
+		}
+		c.MaxLRUSize = size
+		return nil
+	}},
+	{"default_ttl", "TTL of values written through the API, such as 1m or 30s", func(c *CacheConfig, v string) error {
+		ttl, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("%q is not a duration such as 30s or 1m", v)
+		}
+		c.DefaultTTL = ttl
+		return nil
+	}},
+	{"query_timeout", "how long a listing waits for each backend before reporting it as timed out, such as 5s", func(c *CacheConfig, v string) error {
+		timeout, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("%q is not a duration such as 30s or 1m", v)
+		}
+		c.QueryTimeout = timeout
+		return nil
+	}},
+	{"encryption_key_file", "JSON key file used to encrypt values in Redis and memcached", func(c *CacheConfig, v string) error {
+		c.EncryptionKeyFile = v
+		return nil
+	}},
+	{"auth_file", "JSON file of the API keys, their roles and the bearer token secret; the API is open without it", func(c *CacheConfig, v string) error {
+		c.AuthFile = v
+		return nil
+	}},
+	{"peer_self", "base URL of this instance in the cluster cache", func(c *CacheConfig, v string) error {
+		c.PeerSelf = v
+		return nil
+	}},
+	{"peers", "comma separated base URLs of the other cluster cache instances", func(c *CacheConfig, v string) error {
+		c.Peers = splitList(v)
+		return nil
+	}},
+	{"replication_role", "leader or follower to replicate the in-memory cache", func(c *CacheConfig, v string) error {
+		c.ReplicationRole = v
+		return nil
+	}},
+	{"replication_leader", "base URL of the replication leader followed by this instance", func(c *CacheConfig, v string) error {
+		c.ReplicationLeader = v
+		return nil
+	}},
+	{"backends", "comma separated named caches as name=type@address, such as sessions=redis@localhost:6379", func(c *CacheConfig, v string) error {
+		backends, err := parseBackends(v)
+		if err != nil {
+			return err
+		}
+		c.Backends = backends
+		return nil
+	}},
+	{"optional_backends", "comma separated backends, such as redis or memcached, that may be down at startup", func(c *CacheConfig, v string) error {
+		c.OptionalBackends = splitList(v)
+		return nil
+	}},
+	{"breaker_failure_rate", "share of failed calls, from 0 to 1, that opens the circuit breaker of a remote backend", func(c *CacheConfig, v string) error {
+		rate, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", v)
+		}
+		c.Resilience.FailureRate = rate
+		return nil
+	}},
+	{"breaker_min_requests", "calls within the breaker window before the failure rate is checked", func(c *CacheConfig, v string) error {
+		count, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("%q is not an integer", v)
+		}
+		c.Resilience.MinRequests = count
+		return nil
+	}},
+	{"breaker_open_timeout", "how long an open circuit breaker rejects calls, such as 10s", func(c *CacheConfig, v string) error {
+		timeout, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("%q is not a duration such as 30s or 1m", v)
+		}
+		c.Resilience.OpenTimeout = timeout
+		return nil
+	}},
+	{"retry_attempts", "retries of a failed call to a remote backend", func(c *CacheConfig, v string) error {
+		retries, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("%q is not an integer", v)
+		}
+		c.Resilience.Retries = retries
+		return nil
+	}},
+	{"rate_limit_reads", "GET and HEAD requests per client, such as 100/s or 3000/m:500 with a burst of 500", func(c *CacheConfig, v string) error {
+		rate, err := ratelimit.ParseRate(v)
+		if err != nil {
+			return err
+		}
+		c.RateLimit.Reads = rate
+		return nil
+	}},
+	{"rate_limit_writes", "other requests per client, such as 20/s", func(c *CacheConfig, v string) error {
+		rate, err := ratelimit.ParseRate(v)
+		if err != nil {
+			return err
+		}
+		c.RateLimit.Writes = rate
+		return nil
+	}},
+	{"rate_limit_daily_quota", "requests per client and UTC day, 0 for no quota", func(c *CacheConfig, v string) error {
+		quota, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("%q is not an integer", v)
+		}
+		c.RateLimit.DailyQuota = quota
+		return nil
+	}},
+	{"rate_limit_store", "where rate limits are counted: local, or redis to share them across instances", func(c *CacheConfig, v string) error {
+		c.RateLimitStore = v
+		return nil
+	}},
+	{"rate_limit_redis_addr", "host:port of the Redis server shared rate limits are counted in, redis_addr by default", func(c *CacheConfig, v string) error {
+		c.RateLimitRedisAddr = v
+		return nil
+	}},
+	{"rate_limit_redis_db", "Redis database shared rate limits are counted in, apart from the caches in database 0", func(c *CacheConfig, v string) error {
+		db, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("%q is not an integer", v)
+		}
+		c.RateLimitRedisDB = db
+		return nil
+	}},
+}
+
+// Load builds the configuration from, in increasing order of precedence,
+// the defaults, the config file given by -config or CACHE_CONFIG_FILE,
+// CACHE_* environment variables and the command-line flags in args.
+func Load(args []string) (*CacheConfig, error) {
+	flags := flag.NewFlagSet("restapi", flag.ContinueOnError)
+	configFile := flags.String("config", os.Getenv("CACHE_CONFIG_FILE"), "path of a JSON or \"key: value\" config file")
+	flagValues := make(map[string]*string, len(settings))
+	for _, s := range settings {
+		flagValues[s.key] = flags.String(flagName(s.key), "", s.usage)
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+
+	c := Default()
+	c.File = *configFile
+	if *configFile != "" {
+		if err := c.loadFile(*configFile); err != nil {
+			return nil, err
+		}
+	}
+	for _, s := range settings {
+		if value, found := os.LookupEnv(envName(s.key)); found {
+			if err := s.set(c, value); err != nil {
+				return nil, fmt.Errorf("environment variable %s: %w", envName(s.key), err)
+			}
+		}
+	}
+	var flagErr error
+	flags.Visit(func(f *flag.Flag) {
+		for _, s := range settings {
+			if flagName(s.key) == f.Name && flagErr == nil {
+				if err := s.set(c, *flagValues[s.key]); err != nil {
+					flagErr = fmt.Errorf("flag -%s: %w", f.Name, err)
+				}
+			}
+		}
+	})
+	if flagErr != nil {
+		return nil, flagErr
+	}
+
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// loadFile accepts a JSON object, or lines of "key: value" with # comments.
+// List values are comma separated, or JSON arrays.
+func (c *CacheConfig) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	values, err := parseFile(data)
+	if err != nil {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		s, found := lookupSetting(key)
+		if !found {
+			return fmt.Errorf("config file %s: unknown setting %q", path, key)
+		}
+		if err := s.set(c, values[key]); err != nil {
+			return fmt.Errorf("config file %s: %s: %w", path, key, err)
+		}
+	}
+	return nil
+}
+
+func parseFile(data []byte) (map[string]string, error) {
+	values := make(map[string]string)
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		// Numbers are kept as written, so 1000000 is not read as 1e+06.
+		decoder := json.NewDecoder(bytes.NewReader(trimmed))
+		decoder.UseNumber()
+		var raw map[string]interface{}
+		if err := decoder.Decode(&raw); err != nil {
+			return nil, err
+		}
+		if _, err := decoder.Token(); err != io.EOF {
+			return nil, errors.New("unexpected data after the JSON object")
+		}
+		for key, value := range raw {
+			switch v := value.(type) {
+			case []interface{}:
+				items := make([]string, len(v))
+				for i, item := range v {
+					items[i] = fmt.Sprint(item)
+				}
+				values[key] = strings.Join(items, ",")
+			default:
+				values[key] = fmt.Sprint(v)
+			}
+		}
+		return values, nil
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		key, value, found := strings.Cut(text, ":")
+		if !found {
+			return nil, fmt.Errorf("line %d: expected \"key: value\"", line)
+		}
+		values[strings.TrimSpace(key)] = strings.Trim(strings.TrimSpace(value), `"'`)
+	}
+	return values, scanner.Err()
+}
+
+// Validate reports every invalid setting at once.
+func (c *CacheConfig) Validate() error {
+	var problems []string
+	if _, _, err := net.SplitHostPort(c.ListenAddr); err != nil {
+		problems = append(problems, fmt.Sprintf("listen_addr %q is not a host:port address", c.ListenAddr))
+	}
+	if _, _, err := net.SplitHostPort(c.RedisAddr); err != nil {
+		problems = append(problems, fmt.Sprintf("redis_addr %q is not a host:port address", c.RedisAddr))
+	}
+	switch cache.RedisTopology(c.RedisTopology) {
+	case cache.RedisSingle:
+		if len(c.RedisAddrs) > 0 {
+			problems = append(problems, "redis_addrs requires redis_topology sentinel or cluster, a single node is redis_addr")
+		}
+	case cache.RedisSentinel, cache.RedisCluster:
+		if len(c.RedisAddrs) == 0 {
+			problems = append(problems, fmt.Sprintf("redis_topology %s requires redis_addrs", c.RedisTopology))
+		}
+		for _, addr := range c.RedisAddrs {
+			if _, _, err := net.SplitHostPort(addr); err != nil {
+				problems = append(problems, fmt.Sprintf("redis_addrs %q is not a host:port address", addr))
+			}
+		}
+		// Shared rate limits are counted in a database of a single node.
+		if c.RateLimitStore == "redis" && c.RateLimitRedisAddr == "" {
+			problems = append(problems, fmt.Sprintf("rate_limit_store redis with redis_topology %s requires rate_limit_redis_addr", c.RedisTopology))
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("redis_topology must be single, sentinel or cluster, got %q", c.RedisTopology))
+	}
+	if c.RedisTopology == string(cache.RedisSentinel) && c.RedisMasterName == "" {
+		problems = append(problems, "redis_topology sentinel requires redis_master_name")
+	}
+	if c.RedisTopology != string(cache.RedisSentinel) && c.RedisMasterName != "" {
+		problems = append(problems, "redis_master_name requires redis_topology sentinel")
+	}
+	if _, err := cache.ParseMemcachedServers(c.MemcachedServers); err != nil {
+		problems = append(problems, fmt.Sprintf("memcached_servers: %v", err))
+	}
+	if c.MaxLRUSize < 1 {
+		problems = append(problems, fmt.Sprintf("max_lru_size must be at least 1, got %d", c.MaxLRUSize))
+	}
+	if c.DefaultTTL <= 0 {
+		problems = append(problems, fmt.Sprintf("default_ttl must be positive, got %v", c.DefaultTTL))
+	}
+	if c.QueryTimeout <= 0 {
+		problems = append(problems, fmt.Sprintf("query_timeout must be positive, got %v", c.QueryTimeout))
+	}
+	if c.PeerSelf == "" && len(c.Peers) > 0 {
+		problems = append(problems, "peers requires peer_self")
+	}
+	switch c.ReplicationRole {
+	case "", "leader":
+	case "follower":
+		if c.ReplicationLeader == "" {
+			problems = append(problems, "replication_role follower requires replication_leader")
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("replication_role must be leader or follower, got %q", c.ReplicationRole))
+	}
+	if c.Resilience.FailureRate <= 0 || c.Resilience.FailureRate > 1 {
+		problems = append(problems, fmt.Sprintf("breaker_failure_rate must be above 0 and at most 1, got %v", c.Resilience.FailureRate))
+	}
+	if c.Resilience.MinRequests < 1 {
+		problems = append(problems, fmt.Sprintf("breaker_min_requests must be at least 1, got %d", c.Resilience.MinRequests))
+	}
+	if c.Resilience.OpenTimeout <= 0 {
+		problems = append(problems, fmt.Sprintf("breaker_open_timeout must be positive, got %v", c.Resilience.OpenTimeout))
+	}
+	if c.Resilience.Retries < 0 {
+		problems = append(problems, fmt.Sprintf("retry_attempts cannot be negative, got %d", c.Resilience.Retries))
+	}
+	if c.RateLimit.DailyQuota < 0 {
+		problems = append(problems, fmt.Sprintf("rate_limit_daily_quota cannot be negative, got %d", c.RateLimit.DailyQuota))
+	}
+	if c.RateLimitStore != "local" && c.RateLimitStore != "redis" {
+		problems = append(problems, fmt.Sprintf("rate_limit_store must be local or redis, got %q", c.RateLimitStore))
+	}
+	if c.RateLimitRedisAddr != "" {
+		if _, _, err := net.SplitHostPort(c.RateLimitRedisAddr); err != nil {
+			problems = append(problems, fmt.Sprintf("rate_limit_redis_addr %q is not a host:port address", c.RateLimitRedisAddr))
+		}
+	}
+	if c.RateLimitRedisDB < 0 {
+		problems = append(problems, fmt.Sprintf("rate_limit_redis_db cannot be negative, got %d", c.RateLimitRedisDB))
+	}
+	// Clients that may delete cache entries could reset their own limits.
+	if c.RateLimitRedisDB == 0 && c.LimiterRedisAddr() == c.RedisAddr {
+		problems = append(problems, "rate_limit_redis_db cannot be 0, the database of the caches in redis_addr")
+	}
+	names := make(map[string]bool)
+	for _, backend := range c.Backends {
+		if names[backend.Name] {
+			problems = append(problems, fmt.Sprintf("backend %q is defined twice", backend.Name))
+		}
+		names[backend.Name] = true
+		if strings.HasPrefix(backend.Type, "redis") {
+			if _, err := backend.Redis(); err != nil {
+				problems = append(problems, fmt.Sprintf("backend %q: %v", backend.Name, err))
+			}
+		}
+	}
+	names["redis"], names["memcached"] = true, true
+	for _, name := range c.OptionalBackends {
+		if !names[name] {
+			problems = append(problems, fmt.Sprintf("optional backend %q is not redis, memcached or a configured backend", name))
+		}
+	}
+	if len(problems) > 0 {
+		return errors.New("invalid configuration: " + strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+func parseBackends(value string) ([]BackendConfig, error) {
+	var backends []BackendConfig
+	for _, item := range splitList(value) {
+		name, spec, found := strings.Cut(item, "=")
+		if !found || name == "" || spec == "" {
+			return nil, fmt.Errorf("backend %q is not of the form name=type@address", item)
+		}
+		backendType, addr, _ := strings.Cut(spec, "@")
+		backends = append(backends, BackendConfig{
+			Name: strings.TrimSpace(name),
+			Type: strings.TrimSpace(backendType),
+			Addr: strings.TrimSpace(addr),
+		})
+	}
+	return backends, nil
+}
+
+// Redis is how the caches reach Redis: redis_addr for a single node, the
+// redis_addrs otherwise.
+func (c *CacheConfig) Redis() cache.RedisConfig {
+	if c.RedisTopology == string(cache.RedisSingle) {
+		return cache.RedisConfig{Topology: cache.RedisSingle, Addrs: []string{c.RedisAddr}}
+	}
+	return cache.RedisConfig{
+		Topology:   cache.RedisTopology(c.RedisTopology),
+		Addrs:      c.RedisAddrs,
+		MasterName: c.RedisMasterName,
+	}
+}
+
+// Redis is how a backend of type redis reaches its single node, of type
+// redis-cluster its nodes separated by semicolons, and of type
+// redis-sentinel the master monitored by its sentinels, as
+// master@host:port;host:port.
+func (b BackendConfig) Redis() (cache.RedisConfig, error) {
+	config := cache.RedisConfig{Topology: cache.RedisSingle, Addrs: []string{b.Addr}}
+	switch b.Type {
+	case "redis":
+	case "redis-cluster":
+		config = cache.RedisConfig{Topology: cache.RedisCluster, Addrs: splitAddrs(b.Addr)}
+	case "redis-sentinel":
+		master, addrs, found := strings.Cut(b.Addr, "@")
+		if !found || master == "" {
+			return cache.RedisConfig{}, fmt.Errorf("redis-sentinel address %q is not of the form master@host:port;host:port", b.Addr)
+		}
+		config = cache.RedisConfig{Topology: cache.RedisSentinel, Addrs: splitAddrs(addrs), MasterName: master}
+	default:
+		return cache.RedisConfig{}, fmt.Errorf("%q is not a redis backend type", b.Type)
+	}
+	if len(config.Addrs) == 0 {
+		return cache.RedisConfig{}, fmt.Errorf("%s backend requires an address", b.Type)
+	}
+	for _, addr := range config.Addrs {
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			return cache.RedisConfig{}, fmt.Errorf("%q is not a host:port address", addr)
+		}
+	}
+	return config, nil
+}
+
+func splitAddrs(value string) []string {
+	var addrs []string
+	for _, addr := range strings.Split(value, ";") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			addrs = append(addrs, addr)
+		}
+	}
+	return addrs
+}
+
+// LimiterRedisAddr is the Redis server shared rate limits are counted in.
+func (c *CacheConfig) LimiterRedisAddr() string {
+	if c.RateLimitRedisAddr != "" {
+		return c.RateLimitRedisAddr
+	}
+	return c.RedisAddr
+}
+
+// IsOptional reports whether the backend called name may start degraded.
+func (c *CacheConfig) IsOptional(name string) bool {
+	for _, optional := range c.OptionalBackends {
+		if optional == name {
+			return true
+		}
+	}
+	return false
+}
+
+func lookupSetting(key string) (setting, bool) {
+	for _, s := range settings {
+		if s.key == key {
+			return s, true
+		}
+	}
+	return setting{}, false
+}
+
+func envName(key string) string {
+	return "CACHE_" + strings.ToUpper(key)
+}
+
+func flagName(key string) string {
+	return strings.ReplaceAll(key, "_", "-")
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
 }
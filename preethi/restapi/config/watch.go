@@ -0,0 +1,108 @@
+package config
+
+import (
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Watcher loads the configuration again when its file changes or the
+// process receives SIGHUP, and hands valid configurations to apply. A
+// configuration that fails to load is logged and the previous one stays
+// active; apply is expected to reject the changes it cannot make.
+type Watcher struct {
+	args     []string
+	interval time.Duration
+	apply    func(*CacheConfig) error
+
+	mutex   sync.Mutex
+	current *CacheConfig
+	modTime time.Time
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// NewWatcher watches the file of current, which was loaded from args,
+// checking it for changes every interval.
+func NewWatcher(current *CacheConfig, args []string, interval time.Duration, apply func(*CacheConfig) error) *Watcher {
+	w := &Watcher{args: args, interval: interval, apply: apply, current: current}
+	w.modTime = w.fileModTime()
+	return w
+}
+
+func (w *Watcher) Start() {
+	w.stop = make(chan struct{})
+	w.done = make(chan struct{})
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+
+	go func() {
+		defer close(w.done)
+		defer signal.Stop(hangup)
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-w.stop:
+				return
+			case <-hangup:
+				w.reloadAndLog("SIGHUP")
+			case <-ticker.C:
+				if modTime := w.fileModTime(); !modTime.Equal(w.modTime) {
+					w.modTime = modTime
+					w.reloadAndLog("config file change")
+				}
+			}
+		}
+	}()
+}
+
+func (w *Watcher) Stop() {
+	close(w.stop)
+	<-w.done
+}
+
+// Current returns the configuration that is active.
+func (w *Watcher) Current() *CacheConfig {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.current
+}
+
+// Reload loads the configuration and applies it if it is valid.
+func (w *Watcher) Reload() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	cfg, err := Load(w.args)
+	if err != nil {
+		return err
+	}
+	if err := w.apply(cfg); err != nil {
+		return err
+	}
+	w.current = cfg
+	return nil
+}
+
+func (w *Watcher) reloadAndLog(reason string) {
+	if err := w.Reload(); err != nil {
+		log.Printf("Configuration reload after %s failed: %v", reason, err)
+		return
+	}
+	log.Printf("Configuration reloaded after %s", reason)
+}
+
+func (w *Watcher) fileModTime() time.Time {
+	file := w.Current().File
+	if file == "" {
+		return time.Time{}
+	}
+	info, err := os.Stat(file)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
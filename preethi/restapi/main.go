@@ -3,27 +3,72 @@ package main
 import (
 	"log"
 	"net/http"
+	"os"
+	"time"
 
+	"github.com/Preethi0716/Cache-Library/preethi/restapi/config"
 	"github.com/Preethi0716/Cache-Library/preethi/restapi/pkg/api"
-	"github.com/gorilla/mux"
 )
 
+// configPollInterval is how often the config file is checked for changes.
+const configPollInterval = 2 * time.Second
+
 func main() {
 	// Initialize the caches
-	unifiedCache, err := api.InitCache()
+	cfg, err := config.Load(os.Args[1:])
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	unifiedCache, err := api.InitCache(cfg)
 	if err != nil {
 		log.Fatalf("Failed to initialize caches: %v", err)
 	}
+	defer unifiedCache.Close()
 
-	r := mux.NewRouter()
+	// Safe settings are applied again when the config file changes or on SIGHUP.
+	watcher := config.NewWatcher(cfg, os.Args[1:], configPollInterval, unifiedCache.ApplyConfig)
+	watcher.Start()
+	defer watcher.Stop()
 
 	// Register handlers
-	r.HandleFunc("/cache/{key}", api.HandleCacheRequest(unifiedCache)).Methods("GET", "DELETE", "POST")
-	r.HandleFunc("/cache", api.HandleGetAllCacheRequest(unifiedCache)).Methods("GET")
+	r := api.NewRouter(unifiedCache)
 
-	log.Fatal(http.ListenAndServe(":8080", r))
+	log.Fatal(http.ListenAndServe(cfg.ListenAddr, r))
 }
 
+// configuration :: defaults < config file < CACHE_* environment < flags
+// go run . -config cache.conf -max-lru-size 100 -default-ttl 5m
+// CACHE_REDIS_ADDR=localhost:6380 go run .
+// remote backends sit behind a circuit breaker, see breaker_failure_rate,
+// breaker_min_requests, breaker_open_timeout and retry_attempts; its state is in /stats
+// reload -- edit the config file or kill -HUP <pid>; max_lru_size, default_ttl,
+// query_timeout, memcached_servers and peers change live, other settings need a restart;
+// max_lru_size also resizes named lru backends, except those with a capacity such as lru@100
+
+// ttl -- post {"value":"v","ttl":"90s"}, {"ttl":90}, {"ttl":"never"} or
+// {"expires_at":"2030-01-01T00:00:00Z"}, or ?ttl=90s; default_ttl applies otherwise,
+// memcached accepts at most 720h; the response echoes ttl and expires_at
+
+// values -- post {"value":"text"} or any JSON value such as {"value":{"a":[1,2]}} with
+// Content-Type: application/json, or a raw body of any other type, such as
+// curl --data-binary @logo.png -H 'Content-Type: image/png' '.../cache/logo?ttl=1h';
+// get answers with the stored content type, bodies are limited to 1 MiB
+
+// conditional -- get and head answer with ETag, Last-Modified and Cache-Control: max-age
+// from the remaining ttl; send If-None-Match or If-Modified-Since to get 304 when unchanged
+
+// writes -- post creates a key (201, 409 if it exists), put creates (201) or replaces (200),
+// put with If-None-Match: * only creates, with If-Match: <etag> or * only replaces (412 otherwise);
+// patch with Content-Type: application/merge-patch+json merges into a stored JSON value;
+// delete answers 204
+
+// list -- http://localhost:8080/cache lists every backend, grouped by backend with the ttl
+// of each entry; backend (repeated or comma separated), prefix, limit and cursor narrow it.
+// Backends are read in parallel and each group reports its status, so a slow or failing
+// backend shows as "timeout" or "error" while the others are still listed; -query-timeout 5s
+// bounds the wait for each backend, and the cursor retries the ones that did not finish
+
 //Inmemory ::
 // post -- http://localhost:8080/cache/d6
 // get -- http://localhost:8080/cache/d4?cache=inMemory
@@ -33,8 +78,66 @@ func main() {
 // post -- http://localhost:8080/cache/d6
 // get -- http://localhost:8080/cache/d4?cache=redis
 // delete -- http://localhost:8080/cache/d7?cache=redis
+// list -- http://localhost:8080/cache?backend=redis&prefix=d&limit=100&cursor=<cursor from previous page>
+// sentinel -- -redis-topology sentinel -redis-addrs s1:26379,s2:26379 -redis-master-name mymaster
+// cluster -- -redis-topology cluster -redis-addrs n1:7000,n2:7000,n3:7000
 
 // memcached ::
 // post -- http://localhost:8080/cache/d6
 // get -- http://localhost:8080/cache/d4?cache=memcached
 // delete -- http://localhost:8080/cache/d7?cache=memcached
+
+// tiered (in-memory in front of redis) ::
+// post -- http://localhost:8080/cache/d6?cache=tiered
+// get -- http://localhost:8080/cache/d4?cache=tiered
+// delete -- http://localhost:8080/cache/d7?cache=tiered
+
+// cluster (keys sharded over peer instances) ::
+// CACHE_LISTEN_ADDR=:8081 CACHE_PEER_SELF=http://localhost:8081 CACHE_PEERS=http://localhost:8082 go run .
+// CACHE_LISTEN_ADDR=:8082 CACHE_PEER_SELF=http://localhost:8082 CACHE_PEERS=http://localhost:8081 go run .
+// post -- http://localhost:8081/cache/d6?cache=cluster
+// get -- http://localhost:8082/cache/d6?cache=cluster
+// peers -- PUT http://localhost:8081/_peer/peers ["http://localhost:8082","http://localhost:8083"]
+
+// replication (in-memory cache copied from a leader to followers) ::
+// CACHE_LISTEN_ADDR=:8081 CACHE_REPLICATION_ROLE=leader go run .
+// CACHE_LISTEN_ADDR=:8082 CACHE_REPLICATION_ROLE=follower CACHE_REPLICATION_LEADER=http://localhost:8081 go run .
+// post -- http://localhost:8081/cache/d6?cache=inMemory
+// get -- http://localhost:8082/cache/d6?cache=inMemory
+// promote -- POST http://localhost:8082/_replication/promote
+// lag -- http://localhost:8082/stats
+
+// named backends (several caches of one type, see -backends) ::
+// -backends "sessions=redis@localhost:6379,catalog=memcached@localhost:11211;localhost:11212,scratch=lru@100"
+// redis-sentinel@mymaster@s1:26379;s2:26379 and redis-cluster@n1:7000;n2:7000 name other Redis topologies
+// post -- http://localhost:8080/cache/d6?cache=sessions
+// list -- http://localhost:8080/backends
+
+// optional backends (start degraded and reconnect in the background) ::
+// -optional-backends redis,memcached
+// get -- http://localhost:8080/cache/d4?cache=memcached answers 503 with Retry-After while memcached is down
+
+// probes ::
+// liveness -- http://localhost:8080/healthz
+// readiness -- http://localhost:8080/readyz (503 while a required backend is down)
+
+// stats ::
+// get -- http://localhost:8080/stats
+
+// auth (API keys and signed bearer tokens, see -auth-file and pkg/auth) ::
+// -auth-file auth.json with {"token_secret":"<base64 of 32+ bytes>","keys":{"ops":{"key":"<key>",
+// "grants":[{"roles":["admin"]}]},"web":{"key":"<key>","grants":[{"backend":"redis","prefix":"session:","roles":["read","write"]}]}}}
+// get -- curl -H 'X-API-Key: <key>' http://localhost:8080/cache/session:1?cache=redis
+// token -- POST http://localhost:8080/auth/tokens {"subject":"batch","grants":[{"backend":"redis","prefix":"report:","roles":["read"]}],"ttl":"30m"}
+// then send Authorization: Bearer <token>; 401 without valid credentials, 403 without the role;
+// the token's subject becomes <issuing key>/batch and it shares that key's rate limits;
+// tiered stores its keys in redis, so access to tiered also needs the same access to redis;
+// /healthz and /readyz stay open, peers and followers sign their own tokens with the shared secret
+
+// rate limits (token buckets per API key or client IP) ::
+// -rate-limit-reads 100/s -rate-limit-writes 20/s:40 -rate-limit-daily-quota 100000
+// -rate-limit-store redis to share the limits across instances, counted in database 1 of
+// redis_addr apart from the caches, see -rate-limit-redis-addr and -rate-limit-redis-db;
+// requests are limited before authenticating, so invalid credentials count against the client IP
+// responses carry X-RateLimit-Limit, -Remaining and -Reset, and X-RateLimit-Quota-* with a quota;
+// a client over a limit gets 429 with Retry-After
@@ -0,0 +1,206 @@
+// for authenticating clients and checking what they may access
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Preethi0716/Cache-Library/preethi/restapi/pkg/auth"
+	"github.com/gorilla/mux"
+)
+
+// publicPaths answer without credentials so the probes keep working.
+var publicPaths = map[string]bool{"/healthz": true, "/readyz": true}
+
+// Tokens issued through the API are valid for defaultTokenTTL unless the
+// request asks otherwise, and for at most maxTokenTTL.
+const (
+	defaultTokenTTL = time.Hour
+	maxTokenTTL     = 24 * time.Hour
+)
+
+type access struct {
+	role    auth.Role
+	backend string
+	prefix  string
+}
+
+func (a access) String() string {
+	backend := a.backend
+	if backend == "" {
+		backend = "every backend"
+	}
+	if a.prefix == "" {
+		return fmt.Sprintf("%s on all keys in %s", a.role, backend)
+	}
+	return fmt.Sprintf("%s on keys starting with %q in %s", a.role, a.prefix, backend)
+}
+
+// Authorize authenticates every request with unifiedCache.Auth and checks
+// it has the roles its route needs, answering 401 without valid
+// credentials and 403 without the roles:
+//
+//   - GET and HEAD of a key need read on the key in the backend given by
+//     the cache parameter, DELETE needs delete and other methods write.
+//   - Listings need read on the prefix in each listed backend.
+//   - Issuing tokens only needs credentials; the handler checks the grants.
+//   - Every other route, such as /stats and the peer endpoints, needs admin
+//     on every backend.
+//
+// Access to a backend that shares its keys with another, such as tiered
+// with redis, also needs the same access to that other backend.
+//
+// The health probes stay open, as does everything when no auth file is
+// configured.
+func Authorize(unifiedCache *UnifiedCache) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			template := ""
+			if route := mux.CurrentRoute(r); route != nil {
+				template, _ = route.GetPathTemplate()
+			}
+			if unifiedCache.Auth == nil || publicPaths[template] {
+				next.ServeHTTP(w, r)
+				return
+			}
+			// LimitRate has usually authenticated the client already.
+			principal, authenticated := auth.PrincipalFrom(r.Context())
+			if !authenticated {
+				var err error
+				if principal, err = unifiedCache.Auth.Authenticate(r); err != nil {
+					w.Header().Set("WWW-Authenticate", `Bearer realm="cache"`)
+					http.Error(w, err.Error(), http.StatusUnauthorized)
+					return
+				}
+			}
+			for _, required := range withStores(unifiedCache.Backends, requiredAccess(unifiedCache, template, r)) {
+				if !principal.Allows(required.role, required.backend, required.prefix) {
+					http.Error(w, fmt.Sprintf("%s lacks %s", principal.Subject, required), http.StatusForbidden)
+					return
+				}
+			}
+			next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
+		})
+	}
+}
+
+func requiredAccess(unifiedCache *UnifiedCache, template string, r *http.Request) []access {
+	switch template {
+	case "/cache/{key}":
+		role := auth.RoleWrite
+		switch r.Method {
+		case http.MethodGet, http.MethodHead:
+			role = auth.RoleRead
+		case http.MethodDelete:
+			role = auth.RoleDelete
+		}
+		return []access{{role: role, backend: r.URL.Query().Get("cache"), prefix: mux.Vars(r)["key"]}}
+	case "/cache":
+		// An invalid listing is answered with 400 by the handler.
+		request, err := parseListRequest(r, unifiedCache.Backends)
+		if err != nil {
+			return nil
+		}
+		required := make([]access, len(request.backends))
+		for i, backend := range request.backends {
+			required[i] = access{role: auth.RoleRead, backend: backend, prefix: r.URL.Query().Get("prefix")}
+		}
+		return required
+	case "/auth/tokens":
+		return nil
+	}
+	return []access{{role: auth.RoleAdmin}}
+}
+
+// withStores adds the access on the backends the required ones share their
+// keys with, so a grant on tiered does not reach around the grants on redis.
+func withStores(registry *Registry, required []access) []access {
+	var expanded []access
+	for _, a := range required {
+		if a.backend == "" {
+			expanded = append(expanded, a)
+			continue
+		}
+		for _, store := range registry.Stores(a.backend) {
+			expanded = append(expanded, access{role: a.role, backend: store, prefix: a.prefix})
+		}
+	}
+	return expanded
+}
+
+type tokenRequest struct {
+	// Subject defaults to the client asking for the token, and is otherwise
+	// issued as <issuer>/<subject>.
+	Subject string       `json:"subject"`
+	Grants  []auth.Grant `json:"grants"`
+	TTL     string       `json:"ttl"`
+}
+
+type tokenResponse struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// HandleTokenRequest issues a bearer token for the grants in the request,
+// such as {"subject":"batch","grants":[{"backend":"redis","prefix":"report:","roles":["read"]}],"ttl":"30m"}.
+// Clients can only hand out what they administer: each grant needs admin
+// on its backend and prefix. Tokens are bound to the API key they were
+// issued under, whose rate limits they share.
+func HandleTokenRequest(unifiedCache *UnifiedCache) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if unifiedCache.Auth == nil || !unifiedCache.Auth.CanIssue() {
+			http.Error(w, "No token secret is configured", http.StatusNotImplemented)
+			return
+		}
+		principal, ok := auth.PrincipalFrom(r.Context())
+		if !ok {
+			http.Error(w, auth.ErrNoCredentials.Error(), http.StatusUnauthorized)
+			return
+		}
+		var request tokenRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxValueSize)).Decode(&request); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if len(request.Grants) == 0 {
+			http.Error(w, "A token needs at least one grant", http.StatusBadRequest)
+			return
+		}
+		ttl := defaultTokenTTL
+		if request.TTL != "" {
+			var err error
+			ttl, err = time.ParseDuration(request.TTL)
+			if err != nil || ttl <= 0 || ttl > maxTokenTTL {
+				http.Error(w, fmt.Sprintf("ttl must be a duration up to %v", maxTokenTTL), http.StatusBadRequest)
+				return
+			}
+		}
+		issuer := principal.IssuingKey()
+		subject := principal.Subject
+		if request.Subject != "" {
+			subject = issuer + "/" + request.Subject
+		}
+		for _, grant := range request.Grants {
+			required := []access{{role: auth.RoleAdmin, backend: grant.Backend, prefix: grant.Prefix}}
+			for _, required := range withStores(unifiedCache.Backends, required) {
+				if !principal.Allows(required.role, required.backend, required.prefix) {
+					http.Error(w, fmt.Sprintf("%s lacks %s", principal.Subject, required), http.StatusForbidden)
+					return
+				}
+			}
+		}
+
+		token, expiresAt, err := unifiedCache.Auth.Issue(auth.Principal{Subject: subject, Issuer: issuer, Grants: request.Grants}, ttl)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(tokenResponse{Token: token, ExpiresAt: expiresAt})
+	}
+}
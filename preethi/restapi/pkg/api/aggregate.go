@@ -0,0 +1,63 @@
+// for reading several backends at once without waiting on a slow one
+
+package api
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Preethi0716/Cache-Library/preethi/restapi/pkg/cache"
+)
+
+// defaultQueryTimeout bounds how long a listing waits for each backend
+// unless query_timeout changes it.
+const defaultQueryTimeout = 5 * time.Second
+
+// BackendStatus tells whether a backend's part of a result is complete.
+type BackendStatus struct {
+	// Status is "ok", "unavailable", "timeout" or "error".
+	Status  string `json:"status"`
+	Error   string `json:"error,omitempty"`
+	Latency string `json:"latency"`
+}
+
+type backendPage struct {
+	entries map[string]interface{}
+	// expirations holds when the entries that are not typed values expire,
+	// for backends that can tell.
+	expirations map[string]time.Time
+	next        string
+	status      BackendStatus
+}
+
+// queryBackend runs query, giving up after timeout. Caches cannot be
+// cancelled, so a query that times out finishes in the background.
+func queryBackend(timeout time.Duration, query func() (backendPage, error)) backendPage {
+	start := time.Now()
+	done := make(chan backendPage, 1)
+	go func() {
+		page, err := query()
+		page.status = backendStatus(err)
+		done <- page
+	}()
+	var page backendPage
+	select {
+	case page = <-done:
+	case <-time.After(timeout):
+		page.status = BackendStatus{Status: "timeout", Error: fmt.Sprintf("no answer within %v", timeout)}
+	}
+	page.status.Latency = time.Since(start).String()
+	return page
+}
+
+func backendStatus(err error) BackendStatus {
+	switch {
+	case err == nil:
+		return BackendStatus{Status: "ok"}
+	case errors.Is(err, cache.ErrBackendUnavailable):
+		return BackendStatus{Status: "unavailable", Error: err.Error()}
+	default:
+		return BackendStatus{Status: "error", Error: err.Error()}
+	}
+}
@@ -0,0 +1,53 @@
+// for conditional requests and caching headers on cache entries
+
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Preethi0716/Cache-Library/preethi/restapi/pkg/cache"
+)
+
+// entityTag is a strong ETag over the content type and the data, so every
+// backend and instance derives the same tag for the same value.
+func entityTag(contentType string, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(contentType))
+	hash.Write([]byte{0})
+	hash.Write(body)
+	return `"` + hex.EncodeToString(hash.Sum(nil)[:16]) + `"`
+}
+
+// writeValue answers a GET or HEAD with value and its ETag, Last-Modified
+// and Cache-Control headers, the latter from expiresAt unless it is zero.
+// Only typed values know when they were modified. http.ServeContent checks
+// If-None-Match and If-Modified-Since and answers 304 when the client's
+// copy is current.
+func writeValue(w http.ResponseWriter, r *http.Request, value interface{}, expiresAt time.Time) {
+	body, contentType, err := valueBody(value)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	header := w.Header()
+	header.Set("Content-Type", contentType)
+	header.Set("ETag", entityTag(contentType, body))
+
+	var modifiedAt time.Time
+	if typed, ok := value.(cache.TypedValue); ok {
+		modifiedAt = typed.ModifiedAt
+	}
+	if !expiresAt.IsZero() {
+		maxAge := time.Until(expiresAt) / time.Second
+		if maxAge < 0 {
+			maxAge = 0
+		}
+		header.Set("Cache-Control", fmt.Sprintf("max-age=%d", maxAge))
+	}
+	http.ServeContent(w, r, "", modifiedAt, bytes.NewReader(body))
+}
@@ -4,26 +4,73 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"net/http"
-	"preethi/go/src/preethi/restapi/pkg/cache"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/Preethi0716/Cache-Library/preethi/restapi/pkg/auth"
+	"github.com/Preethi0716/Cache-Library/preethi/restapi/pkg/cache"
+	"github.com/Preethi0716/Cache-Library/preethi/restapi/pkg/cluster"
+	"github.com/Preethi0716/Cache-Library/preethi/restapi/pkg/ratelimit"
 	"github.com/gorilla/mux"
 )
 
 type UnifiedCache struct {
-	InMemoryCache  cache.Cache
-	RedisCache     cache.Cache
-	MemcachedCache cache.Cache
+	// Backends holds the caches selected by the cache query parameter.
+	Backends *Registry
+	// Replication is only set when the in-memory cache is replicated.
+	Replication *cluster.ReplicatedCache
+	// Auth is only set when clients have to authenticate.
+	Auth *auth.Authenticator
+	// Limiter is only set when client requests are rate limited.
+	Limiter *ratelimit.Limiter
+
+	// defaultTTL applies to values written through the API, in nanoseconds.
+	defaultTTL atomic.Int64
+	// queryTimeout bounds reads spanning several backends, in nanoseconds.
+	queryTimeout atomic.Int64
+	reload       reloadState
+	health       healthChecker
+	// stop is closed by Close to end the background work.
+	stop     chan struct{}
+	stopOnce sync.Once
 }
 
-func NewUnifiedCache(inMemoryCache, redisCache, memcachedCache cache.Cache) *UnifiedCache {
-	return &UnifiedCache{
-		InMemoryCache:  inMemoryCache,
-		RedisCache:     redisCache,
-		MemcachedCache: memcachedCache,
-	}
+func NewUnifiedCache(backends *Registry) *UnifiedCache {
+	unifiedCache := &UnifiedCache{Backends: backends, stop: make(chan struct{})}
+	unifiedCache.SetDefaultTTL(time.Minute)
+	unifiedCache.SetQueryTimeout(defaultQueryTimeout)
+	return unifiedCache
+}
+
+// Close stops connecting the optional backends that are still down. The
+// backends that are up keep working.
+func (u *UnifiedCache) Close() {
+	u.stopOnce.Do(func() {
+		close(u.stop)
+	})
+}
+
+func (u *UnifiedCache) DefaultTTL() time.Duration {
+	return time.Duration(u.defaultTTL.Load())
+}
+
+func (u *UnifiedCache) SetDefaultTTL(ttl time.Duration) {
+	u.defaultTTL.Store(int64(ttl))
+}
+
+func (u *UnifiedCache) QueryTimeout() time.Duration {
+	return time.Duration(u.queryTimeout.Load())
+}
+
+// SetQueryTimeout sets how long a listing waits for each backend.
+func (u *UnifiedCache) SetQueryTimeout(timeout time.Duration) {
+	u.queryTimeout.Store(int64(timeout))
 }
 
 func HandleCacheRequest(unifiedCache *UnifiedCache) http.HandlerFunc {
@@ -33,52 +80,81 @@ func HandleCacheRequest(unifiedCache *UnifiedCache) http.HandlerFunc {
 		cacheType := r.URL.Query().Get("cache")
 
 		switch r.Method {
-		case "GET":
-			value, err := getCacheValue(unifiedCache, key, cacheType)
+		case "GET", "HEAD":
+			value, expiresAt, err := getCacheValue(unifiedCache, key, cacheType)
 			if err != nil {
-				http.Error(w, err.Error(), http.StatusNotFound)
-				return
-			}
-			w.Write([]byte(value))
-		case "POST":
-			var requestBody map[string]interface{}
-			if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
-				http.Error(w, "Invalid request body", http.StatusBadRequest)
-				return
-			}
-			value, ok := requestBody["value"].(string)
-			if !ok {
-				http.Error(w, "Invalid value format", http.StatusBadRequest)
+				writeError(w, err, http.StatusNotFound)
 				return
 			}
-			ttl := time.Minute
-			err := setCacheValue(unifiedCache, key, value, ttl, cacheType)
-			if err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-				return
-			}
-			w.WriteHeader(http.StatusOK)
+			writeValue(w, r, value, expiresAt)
+		case "POST", "PUT":
+			handleWrite(w, r, unifiedCache, key, cacheType)
+		case "PATCH":
+			handlePatch(w, r, unifiedCache, key, cacheType)
 		case "DELETE":
 			err := deleteCacheValue(unifiedCache, key, cacheType)
 			if err != nil {
-				http.Error(w, err.Error(), http.StatusNotFound)
+				writeError(w, err, http.StatusNotFound)
 				return
 			}
-			w.WriteHeader(http.StatusOK)
+			w.WriteHeader(http.StatusNoContent)
 		default:
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
 	}
 }
 
-func HandleGetAllCacheRequest(unifiedCache *UnifiedCache) http.HandlerFunc {
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	response, err := json.Marshal(body)
+	if err != nil {
+		http.Error(w, "Error encoding response", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(response)
+}
+
+// writeError answers with status, unless the backend is unavailable (503
+// with Retry-After), a read-only replication follower, or the write
+// conflicts with the stored value (409), or a precondition failed (412).
+func writeError(w http.ResponseWriter, err error, status int) {
+	var unavailable *cache.UnavailableError
+	switch {
+	case errors.As(err, &unavailable):
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(unavailable.RetryAfter.Seconds()))))
+		status = http.StatusServiceUnavailable
+	case errors.Is(err, cluster.ErrReadOnly), errors.Is(err, cache.ErrKeyExists),
+		errors.Is(err, cache.ErrUpdateConflict), errors.Is(err, errNotJSONDocument):
+		status = http.StatusConflict
+	case errors.Is(err, errPreconditionFailed):
+		status = http.StatusPreconditionFailed
+	}
+	http.Error(w, err.Error(), status)
+}
+
+func HandleStatsRequest(unifiedCache *UnifiedCache) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		allEntries, err := GetAllCacheEntries(unifiedCache)
+		stats := make(map[string]interface{})
+		unifiedCache.Backends.Each(func(name string, c cache.Cache) error {
+			if provider, ok := c.(cache.StatsProvider); ok {
+				stats[name] = provider.Stats()
+			}
+			return nil
+		})
+		response, err := json.Marshal(stats)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			http.Error(w, "Error encoding response", http.StatusInternalServerError)
 			return
 		}
-		response, err := json.Marshal(allEntries)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(response)
+	}
+}
+
+func HandleBackendsRequest(unifiedCache *UnifiedCache) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		response, err := json.Marshal(unifiedCache.Backends.Backends())
 		if err != nil {
 			http.Error(w, "Error encoding response", http.StatusInternalServerError)
 			return
@@ -88,90 +164,40 @@ func HandleGetAllCacheRequest(unifiedCache *UnifiedCache) http.HandlerFunc {
 	}
 }
 
-func getCacheValue(unifiedCache *UnifiedCache, key string, cacheType string) (string, error) {
-	var value interface{}
-	var err error
-
-	switch cacheType {
-	case "inMemory":
-		value, err = unifiedCache.InMemoryCache.Get(key)
-	case "redis":
-		value, err = unifiedCache.RedisCache.Get(key)
-	case "memcached":
-		value, err = unifiedCache.MemcachedCache.Get(key)
-	default:
-		return "", fmt.Errorf("invalid cache type")
+func cacheByType(unifiedCache *UnifiedCache, cacheType string) (cache.Cache, error) {
+	c, found := unifiedCache.Backends.Get(cacheType)
+	if !found {
+		return nil, fmt.Errorf("invalid cache type %q", cacheType)
 	}
+	return c, nil
+}
 
+// getCacheValue returns the value of key and when it expires, the zero time
+// if it does not or that is unknown. Typed values carry their expiry; for
+// others the backend is asked, if it can tell.
+func getCacheValue(unifiedCache *UnifiedCache, key string, cacheType string) (interface{}, time.Time, error) {
+	c, err := cacheByType(unifiedCache, cacheType)
 	if err != nil {
-		return "", err
+		return nil, time.Time{}, err
 	}
-
-	strValue, ok := value.(string)
-	if !ok {
-		return "", fmt.Errorf("value is not of type string")
+	value, err := c.Get(key)
+	if err != nil {
+		return nil, time.Time{}, err
 	}
-	return strValue, nil
-}
-
-func setCacheValue(unifiedCache *UnifiedCache, key string, value string, ttl time.Duration, cacheType string) error {
-	switch cacheType {
-	case "inMemory":
-		return unifiedCache.InMemoryCache.Set(key, value, ttl)
-	case "redis":
-		return unifiedCache.RedisCache.Set(key, value, ttl)
-	case "memcached":
-		return unifiedCache.MemcachedCache.Set(key, value, ttl)
-	default:
-		return fmt.Errorf("invalid cache type")
+	if typed, ok := value.(cache.TypedValue); ok {
+		if typed.Expired(time.Now()) {
+			return nil, time.Time{}, cache.ErrCacheMiss
+		}
+		return value, typed.ExpiresAt, nil
 	}
+	expirations, _ := cache.Expirations(c, []string{key})
+	return value, expirations[key], nil
 }
 
 func deleteCacheValue(unifiedCache *UnifiedCache, key string, cacheType string) error {
-	switch cacheType {
-	case "inMemory":
-		return unifiedCache.InMemoryCache.Delete(key)
-	case "redis":
-		return unifiedCache.RedisCache.Delete(key)
-	case "memcached":
-		return unifiedCache.MemcachedCache.Delete(key)
-	default:
-		return fmt.Errorf("invalid cache type")
-	}
-}
-
-func GetAllCacheEntries(unifiedCache *UnifiedCache) (map[string]interface{}, error) {
-	allEntries := make(map[string]interface{})
-
-	if unifiedCache.InMemoryCache != nil {
-		lruEntries, err := unifiedCache.InMemoryCache.GetAll()
-		if err != nil {
-			return nil, err
-		}
-		for k, v := range lruEntries {
-			allEntries[k] = v
-		}
-	}
-
-	if unifiedCache.RedisCache != nil {
-		redisEntries, err := unifiedCache.RedisCache.GetAll()
-		if err != nil {
-			return nil, err
-		}
-		for k, v := range redisEntries {
-			allEntries[k] = v
-		}
-	}
-
-	if unifiedCache.MemcachedCache != nil {
-		memcachedEntries, err := unifiedCache.MemcachedCache.GetAll()
-		if err != nil {
-			return nil, err
-		}
-		for k, v := range memcachedEntries {
-			allEntries[k] = v
-		}
+	c, err := cacheByType(unifiedCache, cacheType)
+	if err != nil {
+		return err
 	}
-
-	return allEntries, nil
+	return c.Delete(key)
 }
@@ -0,0 +1,130 @@
+// for the liveness and readiness probes of the orchestrator
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Preethi0716/Cache-Library/preethi/restapi/pkg/cache"
+)
+
+// A backend that does not answer a ping within healthCheckTimeout is down.
+// Results are reused for healthCacheTTL so frequent probes do not load the
+// backends.
+const (
+	healthCheckTimeout = time.Second
+	healthCacheTTL     = 2 * time.Second
+)
+
+type backendHealth struct {
+	Status   string `json:"status"`
+	Latency  string `json:"latency"`
+	Error    string `json:"error,omitempty"`
+	Optional bool   `json:"optional,omitempty"`
+	// Breaker is the circuit breaker state of remote backends.
+	Breaker cache.BreakerState `json:"circuit_breaker,omitempty"`
+}
+
+type readiness struct {
+	Status    string                   `json:"status"`
+	CheckedAt time.Time                `json:"checked_at"`
+	Backends  map[string]backendHealth `json:"backends"`
+	ready     bool
+}
+
+type healthChecker struct {
+	mutex sync.Mutex
+	last  *readiness
+}
+
+func HandleHealthRequest() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"status":"ok"}`))
+	}
+}
+
+// HandleReadyRequest answers 200 when every required backend answers a
+// ping and 503 otherwise. Optional backends are reported but do not make
+// the instance unready.
+func HandleReadyRequest(unifiedCache *UnifiedCache) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		result := unifiedCache.checkReadiness()
+		response, err := json.Marshal(result)
+		if err != nil {
+			http.Error(w, "Error encoding response", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if !result.ready {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		w.Write(response)
+	}
+}
+
+func (u *UnifiedCache) checkReadiness() *readiness {
+	u.health.mutex.Lock()
+	defer u.health.mutex.Unlock()
+	if u.health.last != nil && time.Since(u.health.last.CheckedAt) < healthCacheTTL {
+		return u.health.last
+	}
+
+	cfg := u.currentConfig()
+	result := &readiness{CheckedAt: time.Now(), Backends: make(map[string]backendHealth), ready: true}
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	u.Backends.Each(func(name string, c cache.Cache) error {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			health := pingBackend(c)
+			health.Optional = cfg != nil && cfg.IsOptional(name)
+			mutex.Lock()
+			defer mutex.Unlock()
+			result.Backends[name] = health
+			if health.Status != "up" && !health.Optional {
+				result.ready = false
+			}
+		}()
+		return nil
+	})
+	wg.Wait()
+
+	result.Status = "ready"
+	if !result.ready {
+		result.Status = "not ready"
+	}
+	u.health.last = result
+	return result
+}
+
+func pingBackend(c cache.Cache) backendHealth {
+	var health backendHealth
+	if resilient, ok := c.(*cache.ResilientCache); ok {
+		health.Breaker = resilient.BreakerState()
+	}
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		done <- cache.Ping(c)
+	}()
+	var err error
+	select {
+	case err = <-done:
+	case <-time.After(healthCheckTimeout):
+		err = fmt.Errorf("no answer within %v", healthCheckTimeout)
+	}
+	health.Latency = time.Since(start).String()
+	health.Status = "up"
+	if err != nil {
+		health.Status = "down"
+		health.Error = err.Error()
+	}
+	return health
+}
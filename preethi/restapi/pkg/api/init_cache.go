@@ -4,24 +4,331 @@ package api
 
 import (
 	"fmt"
-	"preethi/go/src/preethi/restapi/pkg/cache"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Preethi0716/Cache-Library/preethi/restapi/config"
+	"github.com/Preethi0716/Cache-Library/preethi/restapi/pkg/auth"
+	"github.com/Preethi0716/Cache-Library/preethi/restapi/pkg/cache"
+	"github.com/Preethi0716/Cache-Library/preethi/restapi/pkg/cluster"
+	"github.com/Preethi0716/Cache-Library/preethi/restapi/pkg/ratelimit"
+)
+
+// tieredL1TTL bounds how long the tiered cache keeps entries in its local level.
+const tieredL1TTL = 10 * time.Second
+
+const invalidationChannel = "cache:invalidations"
+
+// Sizes of the cluster cache: entries owned by this peer, and copies of
+// entries recently read from other peers.
+const (
+	clusterCapacity    = 1000
+	clusterHotCapacity = 100
+	clusterHotTTL      = 5 * time.Second
 )
 
-func InitCache() (*UnifiedCache, error) {
-	inMemoryCache := cache.NewLRUCache(5)
+// replicationLogSize is how many recent operations a replication leader
+// keeps for followers that reconnect.
+const replicationLogSize = 10000
+
+// backendRetryInterval is how often an optional backend that is down is
+// connected again, and the Retry-After given to its clients meanwhile.
+const backendRetryInterval = 5 * time.Second
+
+func InitCache(cfg *config.CacheConfig) (*UnifiedCache, error) {
+	inMemoryCache := cache.NewLRUCache(cfg.MaxLRUSize)
 	if inMemoryCache == nil {
 		return nil, fmt.Errorf("failed to initialize in-memory cache")
 	}
 
-	redisCache, err := cache.NewRedisCache("localhost:6379")
+	// Values stored in the shared caches are encrypted when a key file is configured.
+	var keys *cache.KeyRing
+	if cfg.EncryptionKeyFile != "" {
+		var err error
+		keys, err = cache.LoadKeyRing(cfg.EncryptionKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load encryption keys: %w", err)
+		}
+	}
+	// Clients authenticate when an auth file is configured. Peers and
+	// followers call each other with tokens signed with its secret.
+	var authenticator *auth.Authenticator
+	if cfg.AuthFile != "" {
+		var err error
+		authenticator, err = auth.LoadAuthenticator(cfg.AuthFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load auth file: %w", err)
+		}
+		if !authenticator.CanIssue() && (cfg.PeerSelf != "" || cfg.ReplicationRole != "") {
+			return nil, fmt.Errorf("auth file %s needs a token_secret for the cluster and replication requests", cfg.AuthFile)
+		}
+	}
+	// Remote backends are encrypted, then guarded by a circuit breaker.
+	remote := func(name string, c cache.Cache) cache.Cache {
+		if keys != nil {
+			c = cache.NewEncryptedCache(c, keys)
+		}
+		return cache.NewResilientCache(name, c, cfg.Resilience)
+	}
+
+	// The in-memory cache can be a replication leader, or a follower of
+	// another instance.
+	var replicated *cluster.ReplicatedCache
+	switch cfg.ReplicationRole {
+	case cluster.RoleLeader:
+		replicated = cluster.NewReplicatedLeader(inMemoryCache, replicationLogSize)
+	case cluster.RoleFollower:
+		replicated = cluster.NewReplicatedFollower(inMemoryCache, cfg.ReplicationLeader, replicationLogSize)
+		if authenticator != nil {
+			replicated.SetTransport(authenticator.Transport(nodeID(), nil))
+		}
+		replicated.Start()
+	}
+
+	registry := NewRegistry()
+	registerFactories(registry, cfg, remote)
+	unifiedCache := NewUnifiedCache(registry)
+	unifiedCache.Replication = replicated
+	unifiedCache.Auth = authenticator
+	if cfg.RateLimit.Enabled() {
+		limiter, err := newLimiter(cfg)
+		if err != nil {
+			return nil, err
+		}
+		unifiedCache.Limiter = limiter
+	}
+	unifiedCache.SetDefaultTTL(cfg.DefaultTTL)
+	unifiedCache.SetQueryTimeout(cfg.QueryTimeout)
+	tieredL1 := cache.NewLRUCache(cfg.MaxLRUSize)
+	unifiedCache.reload = reloadState{
+		config:    cfg,
+		lruCaches: []*cache.LRUCache{inMemoryCache, tieredL1},
+	}
+
+	// Until Redis is connected the in-memory cache works without
+	// announcing its changes to the other instances.
+	var localCache cache.Cache = inMemoryCache
+	if replicated != nil {
+		localCache = replicated
+	}
+	registry.Add("inMemory", "lru", localCache)
+
+	// Redis backs the tiered cache and carries the invalidations of the
+	// local caches: writes and deletes are announced to the other
+	// instances, which drop their copies. A replicated in-memory cache is
+	// kept in sync by its leader instead.
+	redisBackends := []BackendInfo{{Name: "redis", Type: "redis"}, {Name: "tiered", Type: "tiered", Store: "redis"}}
+	err := startBackend(unifiedCache.stop, registry, cfg.IsOptional("redis"), redisBackends, func() (map[string]cache.Cache, error) {
+		redisCache, err := cache.NewRedisCacheWithConfig(cfg.Redis())
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize Redis cache: %w", err)
+		}
+		// Each local cache has a channel of its own: the same key in
+		// another cache is a different entry.
+		locals := map[string]*cache.LRUCache{"tiered": tieredL1}
+		if replicated == nil {
+			locals["inMemory"] = inMemoryCache
+		}
+		id := nodeID()
+		buses := make(map[string]*cache.InvalidationBus, len(locals))
+		for name, local := range locals {
+			bus := cache.NewInvalidationBus(redisCache, invalidationChannel+":"+name, id, local)
+			if err := bus.Start(); err != nil {
+				for _, started := range buses {
+					started.Close()
+				}
+				redisCache.Close()
+				return nil, fmt.Errorf("failed to subscribe to cache invalidations: %w", err)
+			}
+			buses[name] = bus
+		}
+		sharedRedis := remote("redis", redisCache)
+		caches := map[string]cache.Cache{
+			"redis":  sharedRedis,
+			"tiered": cache.NewInvalidatingCache(cache.NewTieredCache(tieredL1, sharedRedis, tieredL1TTL), buses["tiered"]),
+		}
+		if replicated == nil {
+			caches["inMemory"] = cache.NewInvalidatingCache(inMemoryCache, buses["inMemory"])
+		}
+		return caches, nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to initialize Redis cache: %w", err)
+		return nil, err
 	}
 
-	memcachedCache, err := cache.NewMemcachedCache("localhost:11211")
+	memcachedBackends := []BackendInfo{{Name: "memcached", Type: "memcached"}}
+	err = startBackend(unifiedCache.stop, registry, cfg.IsOptional("memcached"), memcachedBackends, func() (map[string]cache.Cache, error) {
+		// A reload may have changed the servers while memcached was down.
+		servers, err := cache.ParseMemcachedServers(unifiedCache.currentConfig().MemcachedServers)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize Memcached cache: %w", err)
+		}
+		memcachedCache, err := cache.NewMemcachedCacheWithServers(servers, cache.WithKeyIndex(cache.NewLocalKeyIndex()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize Memcached cache: %w", err)
+		}
+		unifiedCache.setMemcached(memcachedCache)
+		return map[string]cache.Cache{"memcached": remote("memcached", memcachedCache)}, nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to initialize Memcached cache: %w", err)
+		// Stop reconnecting the optional backends started so far.
+		unifiedCache.Close()
+		return nil, err
 	}
 
-	return NewUnifiedCache(inMemoryCache, redisCache, memcachedCache), nil
+	// The cluster cache is shared with the peers when this instance has a
+	// peer URL of its own.
+	if cfg.PeerSelf != "" {
+		peerCache := cluster.NewPeerCache(cfg.PeerSelf, cfg.Peers, clusterCapacity, clusterHotCapacity, clusterHotTTL)
+		if authenticator != nil {
+			peerCache.SetTransport(authenticator.Transport(cfg.PeerSelf, nil))
+		}
+		registry.Add("cluster", "cluster", peerCache)
+	}
+
+	for _, backend := range cfg.Backends {
+		backend := backend
+		if !registry.HasFactory(backend.Type) {
+			unifiedCache.Close()
+			return nil, fmt.Errorf("failed to initialize backend %s: unknown type %q", backend.Name, backend.Type)
+		}
+		err := startBackend(unifiedCache.stop, registry, cfg.IsOptional(backend.Name), []BackendInfo{{Name: backend.Name, Type: backend.Type}}, func() (map[string]cache.Cache, error) {
+			c, err := registry.Build(backend)
+			if err != nil {
+				return nil, fmt.Errorf("failed to initialize backend: %w", err)
+			}
+			// An lru without a capacity of its own follows max_lru_size.
+			if lru, ok := c.(*cache.LRUCache); ok && backend.Addr == "" {
+				unifiedCache.addLRU(lru)
+			}
+			return map[string]cache.Cache{backend.Name: c}, nil
+		})
+		if err != nil {
+			unifiedCache.Close()
+			return nil, err
+		}
+	}
+
+	return unifiedCache, nil
+}
+
+// newLimiter counts the rate limits in memory, or in a Redis database of
+// their own with the memory as a fallback while Redis is unreachable. Redis
+// may be down at startup if it is optional, in which case each instance
+// limits on its own until it is reached.
+func newLimiter(cfg *config.CacheConfig) (*ratelimit.Limiter, error) {
+	local := ratelimit.NewLocalStore(cache.NewLRUCache(rateLimitCapacity))
+	if cfg.RateLimitStore != "redis" {
+		return ratelimit.NewLimiter(cfg.RateLimit, local, nil), nil
+	}
+	store := ratelimit.NewRedisStore(cfg.LimiterRedisAddr(), cfg.RateLimitRedisDB)
+	if err := store.Ping(); err != nil && !cfg.IsOptional("redis") {
+		store.Close()
+		return nil, fmt.Errorf("failed to initialize the rate limit store: %w", err)
+	}
+	return ratelimit.NewLimiter(cfg.RateLimit, store, local), nil
+}
+
+// startBackend registers the backends built by connect, which may also
+// return caches that replace already registered ones. If connect fails for
+// an optional backend, its backends answer with cache.ErrBackendUnavailable
+// and connect is retried in the background until it succeeds or stop is
+// closed.
+func startBackend(stop <-chan struct{}, registry *Registry, optional bool, backends []BackendInfo, connect func() (map[string]cache.Cache, error)) error {
+	caches, err := connect()
+	if err != nil && !optional {
+		return err
+	}
+	for _, backend := range backends {
+		c, found := caches[backend.Name]
+		if !found {
+			c = cache.NewUnavailableCache(backend.Name, err, backendRetryInterval)
+		}
+		if err := registry.AddInfo(backend, c); err != nil {
+			return err
+		}
+		delete(caches, backend.Name)
+	}
+	for name, c := range caches {
+		registry.Replace(name, c)
+	}
+	if err == nil {
+		return nil
+	}
+
+	go func() {
+		ticker := time.NewTicker(backendRetryInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+			caches, err := connect()
+			if err != nil {
+				// Keep the reported error current.
+				for _, backend := range backends {
+					registry.Replace(backend.Name, cache.NewUnavailableCache(backend.Name, err, backendRetryInterval))
+				}
+				continue
+			}
+			for name, c := range caches {
+				registry.Replace(name, c)
+			}
+			return
+		}
+	}()
+	return nil
+}
+
+// registerFactories adds the backend types that can be configured by name.
+// Redis and memcached backends are wrapped by remote like the built-in ones.
+func registerFactories(registry *Registry, cfg *config.CacheConfig, remote func(string, cache.Cache) cache.Cache) {
+	registry.RegisterFactory("lru", func(backend config.BackendConfig) (cache.Cache, error) {
+		capacity := cfg.MaxLRUSize
+		if backend.Addr != "" {
+			var err error
+			if capacity, err = strconv.Atoi(backend.Addr); err != nil || capacity < 1 {
+				return nil, fmt.Errorf("invalid lru capacity %q", backend.Addr)
+			}
+		}
+		return cache.NewLRUCache(capacity), nil
+	})
+	newRedis := func(backend config.BackendConfig) (cache.Cache, error) {
+		redisConfig, err := backend.Redis()
+		if err != nil {
+			return nil, err
+		}
+		c, err := cache.NewRedisCacheWithConfig(redisConfig)
+		if err != nil {
+			return nil, err
+		}
+		return remote(backend.Name, c), nil
+	}
+	for _, backendType := range []string{"redis", "redis-sentinel", "redis-cluster"} {
+		registry.RegisterFactory(backendType, newRedis)
+	}
+	registry.RegisterFactory("memcached", func(backend config.BackendConfig) (cache.Cache, error) {
+		servers, err := cache.ParseMemcachedServers(strings.Split(backend.Addr, ";"))
+		if err != nil {
+			return nil, err
+		}
+		c, err := cache.NewMemcachedCacheWithServers(servers, cache.WithKeyIndex(cache.NewLocalKeyIndex()))
+		if err != nil {
+			return nil, err
+		}
+		return remote(backend.Name, c), nil
+	})
+}
+
+// nodeID identifies this instance on the invalidation channel.
+func nodeID() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	return fmt.Sprintf("%s-%d-%d", hostname, os.Getpid(), time.Now().UnixNano())
 }
@@ -0,0 +1,91 @@
+// for limiting the request rate and daily quota of each client
+
+package api
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Preethi0716/Cache-Library/preethi/restapi/pkg/auth"
+	"github.com/Preethi0716/Cache-Library/preethi/restapi/pkg/ratelimit"
+	"github.com/gorilla/mux"
+)
+
+// rateLimitCapacity bounds the clients whose buckets an instance keeps in
+// memory; the least recently seen start over when it is exceeded.
+const rateLimitCapacity = 100000
+
+// LimitRate counts every request against the limits of its client, its
+// API key or the key its token was issued under, or else its IP address. Responses tell
+// the state of the rate in X-RateLimit-Limit, X-RateLimit-Remaining and
+// X-RateLimit-Reset, and of the daily quota in X-RateLimit-Quota-Limit,
+// X-RateLimit-Quota-Remaining and X-RateLimit-Quota-Reset, all resets in
+// seconds. A client over either limit is answered 429 with Retry-After.
+//
+// The health probes and the endpoints peers call each other on are not
+// limited. It runs before Authorize and authenticates clients itself, so
+// requests with invalid credentials are limited by their IP address; the
+// client it finds is passed on to Authorize.
+func LimitRate(unifiedCache *UnifiedCache) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			template := ""
+			if route := mux.CurrentRoute(r); route != nil {
+				template, _ = route.GetPathTemplate()
+			}
+			if unifiedCache.Limiter == nil || publicPaths[template] || strings.HasPrefix(template, "/_") {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			client := "ip:" + remoteHost(r)
+			if unifiedCache.Auth != nil {
+				if principal, err := unifiedCache.Auth.Authenticate(r); err == nil {
+					client = "key:" + principal.IssuingKey()
+					r = r.WithContext(auth.WithPrincipal(r.Context(), principal))
+				}
+			}
+			write := r.Method != http.MethodGet && r.Method != http.MethodHead
+			rate, quota, err := unifiedCache.Limiter.Allow(client, write)
+			if err != nil {
+				// Limits are best effort: a failing store lets requests through.
+				next.ServeHTTP(w, r)
+				return
+			}
+			if rate != nil {
+				setLimitHeaders(w, "X-RateLimit-", rate)
+			}
+			if quota != nil {
+				setLimitHeaders(w, "X-RateLimit-Quota-", quota)
+			}
+			for _, result := range []*ratelimit.Result{rate, quota} {
+				if result != nil && !result.Allowed {
+					w.Header().Set("Retry-After", strconv.Itoa(int(result.RetryAfter/time.Second)))
+					http.Error(w, fmt.Sprintf("Too many requests, retry in %v", result.RetryAfter), http.StatusTooManyRequests)
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func setLimitHeaders(w http.ResponseWriter, prefix string, result *ratelimit.Result) {
+	w.Header().Set(prefix+"Limit", strconv.Itoa(result.Limit))
+	w.Header().Set(prefix+"Remaining", strconv.Itoa(result.Remaining))
+	w.Header().Set(prefix+"Reset", strconv.Itoa(int(result.Reset/time.Second)))
+}
+
+// remoteHost is the IP address r came from. Forwarding headers are ignored
+// since any client can set them.
+func remoteHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,273 @@
+// for listing cache entries page by page, grouped by backend
+
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Preethi0716/Cache-Library/preethi/restapi/pkg/cache"
+)
+
+// Page sizes accepted by GET /cache?limit=<n>.
+const (
+	defaultPageSize = 100
+	maxPageSize     = 1000
+)
+
+type listRequest struct {
+	backends []string
+	match    string
+	limit    int
+	// cursors holds where each listed backend continues.
+	cursors map[string]string
+}
+
+type listedEntry struct {
+	Key   string      `json:"key"`
+	Value interface{} `json:"value"`
+	// TTL and ExpiresAt are known for values written through the API, and
+	// for any value of a backend that tracks expiry, such as Redis.
+	TTL       string     `json:"ttl,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	Error     string     `json:"error,omitempty"`
+}
+
+// HandleGetAllCacheRequest lists entries grouped by backend:
+//
+//	{"backends":[{"backend":"redis","status":"ok","latency":"1ms","entries":[{"key":..,"value":..,"ttl":..}]}],"cursor":".."}
+//
+// backend (repeated or comma separated) selects backends, prefix filters
+// keys and limit bounds the entries per backend of a page; an empty cursor
+// means the listing is complete, otherwise it is passed back to get the
+// next page. Backends that scan in batches may return a few more entries
+// than limit.
+//
+// The backends are read concurrently, each within the query timeout, and
+// streamed in order. A backend that fails or times out is reported in its
+// status and error, so the other backends' entries still arrive, and stays
+// in the cursor where it was read from; only backends whose scan is
+// complete are left out of it.
+func HandleGetAllCacheRequest(unifiedCache *UnifiedCache) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		request, err := parseListRequest(r, unifiedCache.Backends)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		streamListing(w, unifiedCache, request)
+	}
+}
+
+func parseListRequest(r *http.Request, registry *Registry) (listRequest, error) {
+	query := r.URL.Query()
+	request := listRequest{limit: defaultPageSize, cursors: make(map[string]string)}
+	if prefix := query.Get("prefix"); prefix != "" {
+		request.match = escapeGlob(prefix) + "*"
+	}
+	if raw := query.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit <= 0 || limit > maxPageSize {
+			return request, fmt.Errorf("limit must be between 1 and %d", maxPageSize)
+		}
+		request.limit = limit
+	}
+
+	// The cache parameter selects backends everywhere else in the API.
+	var selected []string
+	for _, values := range [][]string{query["backend"], query["cache"]} {
+		for _, value := range values {
+			for _, name := range strings.Split(value, ",") {
+				if name = strings.TrimSpace(name); name != "" {
+					selected = append(selected, name)
+				}
+			}
+		}
+	}
+	var names []string
+	for _, info := range registry.Backends() {
+		names = append(names, info.Name)
+	}
+	for _, name := range selected {
+		if !slices.Contains(names, name) {
+			return request, fmt.Errorf("invalid cache type %q", name)
+		}
+	}
+	if raw := query.Get("cursor"); raw != "" {
+		data, err := base64.RawURLEncoding.DecodeString(raw)
+		if err != nil || json.Unmarshal(data, &request.cursors) != nil {
+			return request, fmt.Errorf("invalid cursor")
+		}
+		for name := range request.cursors {
+			if !slices.Contains(names, name) {
+				return request, fmt.Errorf("cursor backend %q is not registered", name)
+			}
+		}
+	}
+
+	for _, name := range names {
+		if len(selected) > 0 && !slices.Contains(selected, name) {
+			continue
+		}
+		if _, found := request.cursors[name]; found || query.Get("cursor") == "" {
+			request.backends = append(request.backends, name)
+		}
+	}
+	return request, nil
+}
+
+func streamListing(w http.ResponseWriter, unifiedCache *UnifiedCache, request listRequest) {
+	timeout := unifiedCache.QueryTimeout()
+	pages := make([]chan backendPage, len(request.backends))
+	for i, name := range request.backends {
+		pages[i] = make(chan backendPage, 1)
+		c, _ := unifiedCache.Backends.Get(name)
+		cursor := request.cursors[name]
+		go func(page chan<- backendPage) {
+			page <- queryBackend(timeout, func() (backendPage, error) {
+				entries, next, err := scanPage(c, cursor, request.match, request.limit)
+				if err != nil {
+					return backendPage{}, err
+				}
+				return backendPage{entries: entries, expirations: untypedExpirations(c, entries), next: next}, nil
+			})
+		}(pages[i])
+	}
+
+	flusher, _ := w.(http.Flusher)
+	io.WriteString(w, `{"backends":[`)
+	next := make(map[string]string)
+	for i, name := range request.backends {
+		page := <-pages[i]
+		if i > 0 {
+			io.WriteString(w, ",")
+		}
+		fmt.Fprintf(w, `{"backend":%s,"status":%s,`, mustMarshal(name), mustMarshal(page.status.Status))
+		if page.status.Error != "" {
+			fmt.Fprintf(w, `"error":%s,`, mustMarshal(page.status.Error))
+		}
+		fmt.Fprintf(w, `"latency":%s,"entries":[`, mustMarshal(page.status.Latency))
+		keys := make([]string, 0, len(page.entries))
+		for key, value := range page.entries {
+			if typed, ok := value.(cache.TypedValue); ok && typed.Expired(time.Now()) {
+				continue
+			}
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for j, key := range keys {
+			if j > 0 {
+				io.WriteString(w, ",")
+			}
+			expiresAt, known := page.expirations[key]
+			w.Write(listEntry(key, page.entries[key], expiresAt, known))
+		}
+		io.WriteString(w, "]}")
+		if flusher != nil {
+			flusher.Flush()
+		}
+		switch {
+		case page.status.Status != "ok":
+			next[name] = request.cursors[name]
+		case page.next != "":
+			next[name] = page.next
+		}
+	}
+
+	nextCursor := ""
+	if len(next) > 0 {
+		nextCursor = base64.RawURLEncoding.EncodeToString(mustMarshal(next))
+	}
+	fmt.Fprintf(w, `],"cursor":%s}`, mustMarshal(nextCursor))
+}
+
+// scanPage scans c from cursor until it has about limit entries or the
+// scan is complete; Redis may return empty batches with a cursor to go on.
+func scanPage(c cache.Cache, cursor, match string, limit int) (map[string]interface{}, string, error) {
+	page := make(map[string]interface{})
+	for {
+		entries, next, err := cache.Scan(c, cursor, match, limit-len(page))
+		if err != nil {
+			return nil, "", err
+		}
+		for key, value := range entries {
+			page[key] = value
+		}
+		if next == "" || len(page) >= limit {
+			return page, next, nil
+		}
+		cursor = next
+	}
+}
+
+// untypedExpirations asks c when the entries that are not typed values
+// expire. The expirations are only informative, so errors leave them unknown.
+func untypedExpirations(c cache.Cache, entries map[string]interface{}) map[string]time.Time {
+	var keys []string
+	for key, value := range entries {
+		if _, ok := value.(cache.TypedValue); !ok {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	expirations, err := cache.Expirations(c, keys)
+	if err != nil {
+		return nil
+	}
+	return expirations
+}
+
+// listEntry encodes an entry, with its expiry when it is known: from a typed
+// value, or else from the backend.
+func listEntry(key string, value interface{}, expiresAt time.Time, known bool) []byte {
+	entry := listedEntry{Key: key, Value: value}
+	if typed, ok := value.(cache.TypedValue); ok {
+		expiresAt, known = typed.ExpiresAt, true
+	}
+	if known {
+		entry.TTL = noExpiry
+		if !expiresAt.IsZero() {
+			expiresAt = expiresAt.UTC()
+			entry.TTL = time.Until(expiresAt).Round(time.Second).String()
+			entry.ExpiresAt = &expiresAt
+		}
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		data = mustMarshal(listedEntry{Key: key, Error: err.Error()})
+	}
+	return data
+}
+
+// mustMarshal encodes values that always encode, such as strings.
+func mustMarshal(value interface{}) []byte {
+	data, err := json.Marshal(value)
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
+
+// escapeGlob quotes the characters path.Match and Redis MATCH treat as
+// patterns.
+func escapeGlob(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(`*?[]\`, r) {
+			b.WriteRune('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
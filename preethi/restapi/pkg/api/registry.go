@@ -0,0 +1,184 @@
+// for looking up the caches served by the API by name
+
+package api
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+
+	"github.com/Preethi0716/Cache-Library/preethi/restapi/config"
+	"github.com/Preethi0716/Cache-Library/preethi/restapi/pkg/cache"
+)
+
+// BackendFactory creates a cache of one type from its configuration.
+type BackendFactory func(cfg config.BackendConfig) (cache.Cache, error)
+
+type BackendInfo struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	// Store names the backend whose keys this one reads and writes, such
+	// as redis for tiered, so access to this backend is access to it too.
+	Store string `json:"store,omitempty"`
+	// Status is "degraded" while the backend waits to reconnect.
+	Status string `json:"status"`
+}
+
+type backend struct {
+	BackendInfo
+	cache cache.Cache
+}
+
+// Registry maps backend names, as used in the cache query parameter, to
+// caches. Several backends may share a type, each with its own settings.
+type Registry struct {
+	mutex     sync.RWMutex
+	factories map[string]BackendFactory
+	backends  []backend
+}
+
+func NewRegistry() *Registry {
+	return &Registry{factories: make(map[string]BackendFactory)}
+}
+
+func (r *Registry) RegisterFactory(backendType string, factory BackendFactory) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.factories[backendType] = factory
+}
+
+// Create builds a backend with the factory registered for its type and
+// registers it.
+func (r *Registry) Create(cfg config.BackendConfig) (cache.Cache, error) {
+	c, err := r.Build(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.Add(cfg.Name, cfg.Type, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Build builds a backend with the factory registered for its type, without
+// registering it.
+func (r *Registry) Build(cfg config.BackendConfig) (cache.Cache, error) {
+	r.mutex.RLock()
+	factory, found := r.factories[cfg.Type]
+	r.mutex.RUnlock()
+	if !found {
+		return nil, fmt.Errorf("backend %s: unknown type %q", cfg.Name, cfg.Type)
+	}
+	c, err := factory(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("backend %s: %w", cfg.Name, err)
+	}
+	return c, nil
+}
+
+func (r *Registry) HasFactory(backendType string) bool {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	_, found := r.factories[backendType]
+	return found
+}
+
+// Add registers an existing cache under name.
+func (r *Registry) Add(name, backendType string, c cache.Cache) error {
+	return r.AddInfo(BackendInfo{Name: name, Type: backendType}, c)
+}
+
+// AddInfo registers an existing cache described by info, which may name
+// the backend it shares its keys with.
+func (r *Registry) AddInfo(info BackendInfo, c cache.Cache) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for _, b := range r.backends {
+		if b.Name == info.Name {
+			return fmt.Errorf("backend %s is already registered", info.Name)
+		}
+	}
+	info.Status = ""
+	r.backends = append(r.backends, backend{BackendInfo: info, cache: c})
+	return nil
+}
+
+// Replace swaps the cache of a registered backend, such as a reconnected
+// backend for its placeholder.
+func (r *Registry) Replace(name string, c cache.Cache) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for i := range r.backends {
+		if r.backends[i].Name == name {
+			r.backends[i].cache = c
+			return nil
+		}
+	}
+	return fmt.Errorf("backend %s is not registered", name)
+}
+
+func (r *Registry) Get(name string) (cache.Cache, bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	for _, b := range r.backends {
+		if b.Name == name {
+			return b.cache, true
+		}
+	}
+	return nil, false
+}
+
+// Stores returns name followed by the backends whose keys it reads and
+// writes, following Store.
+func (r *Registry) Stores(name string) []string {
+	stores := []string{name}
+	for info, found := r.Info(name); found && info.Store != ""; info, found = r.Info(info.Store) {
+		if slices.Contains(stores, info.Store) {
+			break
+		}
+		stores = append(stores, info.Store)
+	}
+	return stores
+}
+
+// Info describes a registered backend.
+func (r *Registry) Info(name string) (BackendInfo, bool) {
+	for _, info := range r.Backends() {
+		if info.Name == name {
+			return info, true
+		}
+	}
+	return BackendInfo{}, false
+}
+
+// Backends lists the registered backends sorted by name.
+func (r *Registry) Backends() []BackendInfo {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	infos := make([]BackendInfo, len(r.backends))
+	for i, b := range r.backends {
+		infos[i] = b.BackendInfo
+		infos[i].Status = "available"
+		if _, degraded := b.cache.(*cache.UnavailableCache); degraded {
+			infos[i].Status = "degraded"
+		}
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name < infos[j].Name
+	})
+	return infos
+}
+
+// Each calls fn with every backend in registration order.
+func (r *Registry) Each(fn func(name string, c cache.Cache) error) error {
+	r.mutex.RLock()
+	backends := append([]backend(nil), r.backends...)
+	r.mutex.RUnlock()
+	for _, b := range backends {
+		if err := fn(b.Name, b.cache); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+// for applying a reloaded configuration to the running caches
+
+package api
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/Preethi0716/Cache-Library/preethi/restapi/config"
+	"github.com/Preethi0716/Cache-Library/preethi/restapi/pkg/cache"
+	"github.com/Preethi0716/Cache-Library/preethi/restapi/pkg/cluster"
+)
+
+// reloadState holds what ApplyConfig changes in place.
+type reloadState struct {
+	mutex     sync.Mutex
+	config    *config.CacheConfig
+	lruCaches []*cache.LRUCache
+	memcached *cache.MemcachedCache
+}
+
+func (u *UnifiedCache) currentConfig() *config.CacheConfig {
+	u.reload.mutex.Lock()
+	defer u.reload.mutex.Unlock()
+	return u.reload.config
+}
+
+func (u *UnifiedCache) addLRU(lru *cache.LRUCache) {
+	u.reload.mutex.Lock()
+	defer u.reload.mutex.Unlock()
+	u.reload.lruCaches = append(u.reload.lruCaches, lru)
+}
+
+func (u *UnifiedCache) setMemcached(memcachedCache *cache.MemcachedCache) {
+	u.reload.mutex.Lock()
+	defer u.reload.mutex.Unlock()
+	u.reload.memcached = memcachedCache
+}
+
+// ApplyConfig applies the settings that can change while running: the
+// in-memory cache capacity, also of the named lru backends configured
+// without a capacity of their own, the default TTL, the query timeout, the
+// memcached servers and the cluster peers. A configuration that changes any
+// other setting is rejected as a whole, since those only take effect after a
+// restart.
+func (u *UnifiedCache) ApplyConfig(cfg *config.CacheConfig) error {
+	u.reload.mutex.Lock()
+	defer u.reload.mutex.Unlock()
+
+	current := u.reload.config
+	if current == nil {
+		return fmt.Errorf("caches were not initialized from a configuration")
+	}
+	for _, check := range []struct {
+		setting string
+		changed bool
+	}{
+		{"listen_addr", current.ListenAddr != cfg.ListenAddr},
+		{"redis_addr", current.RedisAddr != cfg.RedisAddr},
+		{"redis_topology, redis_addrs and redis_master_name", current.RedisTopology != cfg.RedisTopology ||
+			!reflect.DeepEqual(current.RedisAddrs, cfg.RedisAddrs) || current.RedisMasterName != cfg.RedisMasterName},
+		{"encryption_key_file", current.EncryptionKeyFile != cfg.EncryptionKeyFile},
+		{"auth_file", current.AuthFile != cfg.AuthFile},
+		{"peer_self", current.PeerSelf != cfg.PeerSelf},
+		{"replication_role", current.ReplicationRole != cfg.ReplicationRole},
+		{"replication_leader", current.ReplicationLeader != cfg.ReplicationLeader},
+		{"backends", !reflect.DeepEqual(current.Backends, cfg.Backends)},
+		{"optional_backends", !reflect.DeepEqual(current.OptionalBackends, cfg.OptionalBackends)},
+		{"breaker_* and retry_attempts", current.Resilience != cfg.Resilience},
+		{"rate_limit_*", current.RateLimit != cfg.RateLimit || current.RateLimitStore != cfg.RateLimitStore ||
+			current.RateLimitRedisAddr != cfg.RateLimitRedisAddr || current.RateLimitRedisDB != cfg.RateLimitRedisDB},
+	} {
+		if check.changed {
+			return fmt.Errorf("%s cannot change without a restart", check.setting)
+		}
+	}
+
+	// A memcached that is still down picks up the servers when it connects.
+	if u.reload.memcached != nil && !reflect.DeepEqual(current.MemcachedServers, cfg.MemcachedServers) {
+		servers, err := cache.ParseMemcachedServers(cfg.MemcachedServers)
+		if err != nil {
+			return err
+		}
+		if err := u.reload.memcached.SetServers(servers); err != nil {
+			return fmt.Errorf("failed to update memcached servers: %w", err)
+		}
+	}
+	for _, lru := range u.reload.lruCaches {
+		lru.Resize(cfg.MaxLRUSize)
+	}
+	u.SetDefaultTTL(cfg.DefaultTTL)
+	u.SetQueryTimeout(cfg.QueryTimeout)
+	u.reload.config = cfg
+
+	// Peers change last: the new membership applies even when some entries
+	// could not be handed over, which is reported as an error.
+	clusterCache, _ := u.Backends.Get("cluster")
+	if peerCache, ok := clusterCache.(*cluster.PeerCache); ok && !reflect.DeepEqual(current.Peers, cfg.Peers) {
+		if err := peerCache.SetPeers(cfg.Peers); err != nil {
+			return fmt.Errorf("cluster peers updated, but %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+// for routing the requests of the API to their handlers
+
+package api
+
+import (
+	"github.com/Preethi0716/Cache-Library/preethi/restapi/pkg/cluster"
+	"github.com/gorilla/mux"
+)
+
+// NewRouter serves the API of unifiedCache: the cache, the probes and
+// statistics, issuing tokens, and the internal endpoints of the cluster and
+// replication when they are enabled.
+func NewRouter(unifiedCache *UnifiedCache) *mux.Router {
+	r := mux.NewRouter()
+	r.HandleFunc("/cache/{key}", HandleCacheRequest(unifiedCache)).Methods("GET", "HEAD", "DELETE", "POST", "PUT", "PATCH")
+	r.HandleFunc("/cache", HandleGetAllCacheRequest(unifiedCache)).Methods("GET")
+	r.HandleFunc("/stats", HandleStatsRequest(unifiedCache)).Methods("GET")
+	r.HandleFunc("/backends", HandleBackendsRequest(unifiedCache)).Methods("GET")
+	r.HandleFunc("/healthz", HandleHealthRequest()).Methods("GET")
+	r.HandleFunc("/readyz", HandleReadyRequest(unifiedCache)).Methods("GET")
+	r.HandleFunc("/auth/tokens", HandleTokenRequest(unifiedCache)).Methods("POST")
+	clusterCache, _ := unifiedCache.Backends.Get("cluster")
+	if peerCache, ok := clusterCache.(*cluster.PeerCache); ok {
+		peerCache.RegisterRoutes(r)
+	}
+	if unifiedCache.Replication != nil {
+		unifiedCache.Replication.RegisterRoutes(r)
+	}
+
+	// Rate limits and quotas apply per API key, or per client IP without
+	// valid credentials, before anything else is done for a request.
+	r.Use(LimitRate(unifiedCache))
+	// Clients authenticate and are checked against their roles when an auth file is configured.
+	r.Use(Authorize(unifiedCache))
+	return r
+}
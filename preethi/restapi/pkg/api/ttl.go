@@ -0,0 +1,115 @@
+// for choosing how long a value written through the API lives
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Preethi0716/Cache-Library/preethi/restapi/pkg/cache"
+)
+
+// maxTTLs caps the ttl per backend type. Memcached reads relative
+// expirations beyond 30 days as Unix timestamps.
+var maxTTLs = map[string]time.Duration{
+	"memcached": cache.MemcachedMaxTTL,
+}
+
+// noExpiry is accepted as a ttl for values that never expire, as is "0".
+const noExpiry = "never"
+
+// expiry is the lifetime chosen for a written value; a zero ttl means the
+// value does not expire.
+type expiry struct {
+	ttl       time.Duration
+	expiresAt time.Time
+}
+
+type writeResponse struct {
+	Key       string     `json:"key"`
+	Backend   string     `json:"backend"`
+	TTL       string     `json:"ttl"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+func newWriteResponse(key, backend string, e expiry) writeResponse {
+	response := writeResponse{Key: key, Backend: backend, TTL: noExpiry}
+	if e.ttl != 0 {
+		expiresAt := e.expiresAt.UTC()
+		response.TTL = e.ttl.String()
+		response.ExpiresAt = &expiresAt
+	}
+	return response
+}
+
+// requestExpiry reads the lifetime of a write from the ttl or expires_at
+// field of the body, falling back to the query parameters of the same name
+// and then to the configured default. A ttl is a duration such as "90s",
+// a number of seconds, or "never"; expires_at is an RFC 3339 time.
+func (u *UnifiedCache) requestExpiry(r *http.Request, rawTTL json.RawMessage, rawExpiresAt *string, cacheType string) (expiry, error) {
+	ttl, expiresAt := "", ""
+	if len(rawTTL) > 0 && string(rawTTL) != "null" {
+		// A number of seconds is kept as its text.
+		var seconds json.Number
+		if err := json.Unmarshal(rawTTL, &ttl); err != nil {
+			if err := json.Unmarshal(rawTTL, &seconds); err != nil {
+				return expiry{}, fmt.Errorf("ttl must be a duration, a number of seconds or %q", noExpiry)
+			}
+			ttl = seconds.String()
+		}
+	} else {
+		ttl = r.URL.Query().Get("ttl")
+	}
+	if rawExpiresAt != nil {
+		expiresAt = *rawExpiresAt
+	} else {
+		expiresAt = r.URL.Query().Get("expires_at")
+	}
+
+	now := time.Now()
+	var e expiry
+	switch {
+	case ttl != "" && expiresAt != "":
+		return expiry{}, fmt.Errorf("set either ttl or expires_at, not both")
+	case expiresAt != "":
+		at, err := time.Parse(time.RFC3339, expiresAt)
+		if err != nil {
+			return expiry{}, fmt.Errorf("expires_at must be an RFC 3339 time: %v", err)
+		}
+		if !at.After(now) {
+			return expiry{}, fmt.Errorf("expires_at %s is in the past", expiresAt)
+		}
+		e = expiry{ttl: at.Sub(now), expiresAt: at}
+	case ttl == noExpiry || ttl == "0":
+		e = expiry{}
+	case ttl != "":
+		d, err := parseTTL(ttl)
+		if err != nil {
+			return expiry{}, fmt.Errorf("ttl must be a duration, a number of seconds or %q", noExpiry)
+		}
+		if d <= 0 {
+			return expiry{}, fmt.Errorf("ttl must be positive, or %q for no expiry", noExpiry)
+		}
+		e = expiry{ttl: d, expiresAt: now.Add(d)}
+	default:
+		d := u.DefaultTTL()
+		e = expiry{ttl: d, expiresAt: cache.ExpiresAt(d)}
+	}
+
+	if info, found := u.Backends.Info(cacheType); found {
+		if limit, limited := maxTTLs[info.Type]; limited && e.ttl > limit {
+			return expiry{}, fmt.Errorf("ttl %v exceeds the %v limit of %s backends", e.ttl.Round(time.Second), limit, info.Type)
+		}
+	}
+	return e, nil
+}
+
+func parseTTL(ttl string) (time.Duration, error) {
+	if seconds, err := strconv.ParseFloat(ttl, 64); err == nil {
+		return time.Duration(seconds * float64(time.Second)), nil
+	}
+	return time.ParseDuration(ttl)
+}
@@ -0,0 +1,97 @@
+// for converting request bodies to cached values and back
+
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Preethi0716/Cache-Library/preethi/restapi/pkg/cache"
+)
+
+// maxValueSize bounds request bodies; memcached rejects larger items anyway.
+const maxValueSize = 1 << 20
+
+const (
+	jsonContentType = "application/json"
+	textContentType = "text/plain; charset=utf-8"
+)
+
+// errValueTooLarge is answered with 413.
+var errValueTooLarge = fmt.Errorf("value exceeds %d bytes", maxValueSize)
+
+type writeRequest struct {
+	Value     json.RawMessage `json:"value"`
+	TTL       json.RawMessage `json:"ttl"`
+	ExpiresAt *string         `json:"expires_at"`
+}
+
+// readWriteRequest reads the value to store. A JSON body, or one without a
+// content type, is a {"value": ...} envelope: strings are stored as text
+// and other JSON values as JSON documents. Any other body is stored as is
+// with its content type, and its ttl comes from the query.
+//
+// Text is stored as a plain string, as it always was, so other clients of
+// the backends keep reading it; documents and other content are stored as
+// cache.TypedValue.
+func readWriteRequest(w http.ResponseWriter, r *http.Request) (interface{}, writeRequest, error) {
+	var request writeRequest
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxValueSize))
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return nil, request, errValueTooLarge
+	}
+	if err != nil {
+		return nil, request, fmt.Errorf("Invalid request body")
+	}
+
+	contentType := r.Header.Get("Content-Type")
+	mediaType, params, _ := mime.ParseMediaType(contentType)
+	if mediaType == "text/plain" && (params["charset"] == "" || strings.EqualFold(params["charset"], "utf-8")) && utf8.Valid(body) {
+		return string(body), request, nil
+	}
+	if contentType != "" && mediaType != jsonContentType {
+		return cache.TypedValue{ContentType: contentType, Data: body}, request, nil
+	}
+
+	if err := json.Unmarshal(body, &request); err != nil {
+		return nil, request, fmt.Errorf("Invalid request body")
+	}
+	if len(request.Value) == 0 || string(request.Value) == "null" {
+		return nil, request, fmt.Errorf("Invalid value format")
+	}
+	var text string
+	if err := json.Unmarshal(request.Value, &text); err == nil {
+		return text, request, nil
+	}
+	var document bytes.Buffer
+	if err := json.Compact(&document, request.Value); err != nil {
+		return nil, request, fmt.Errorf("Invalid value format")
+	}
+	return cache.TypedValue{ContentType: jsonContentType, Data: document.Bytes()}, request, nil
+}
+
+// valueBody is the response body and content type for a cached value.
+func valueBody(value interface{}) ([]byte, string, error) {
+	switch v := value.(type) {
+	case string:
+		return []byte(v), textContentType, nil
+	case []byte:
+		return v, "application/octet-stream", nil
+	case cache.TypedValue:
+		return v.Data, v.ContentType, nil
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return nil, "", fmt.Errorf("value of type %T cannot be encoded: %v", value, err)
+		}
+		return data, jsonContentType, nil
+	}
+}
@@ -0,0 +1,225 @@
+// for creating, replacing and patching cache entries
+
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Preethi0716/Cache-Library/preethi/restapi/pkg/cache"
+)
+
+const mergePatchContentType = "application/merge-patch+json"
+
+var (
+	// errPreconditionFailed is answered with 412.
+	errPreconditionFailed = errors.New("the value does not match If-Match")
+	// errNotJSONDocument is answered with 409, as only JSON can be patched.
+	errNotJSONDocument = errors.New("the value is not a JSON document")
+)
+
+// handleWrite stores the value of a POST or PUT. POST, and PUT with
+// If-None-Match: *, only create the key and answer 409 if it exists. PUT
+// creates or replaces the key; with If-Match it only replaces a value whose
+// ETag matches, or any value for *.
+func handleWrite(w http.ResponseWriter, r *http.Request, unifiedCache *UnifiedCache, key, cacheType string) {
+	value, requestBody, err := readWriteRequest(w, r)
+	if errors.Is(err, errValueTooLarge) {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	e, err := unifiedCache.requestExpiry(r, requestBody.TTL, requestBody.ExpiresAt, cacheType)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if typed, ok := value.(cache.TypedValue); ok {
+		typed.ModifiedAt, typed.ExpiresAt = time.Now(), e.expiresAt
+		value = typed
+	}
+	c, err := cacheByType(unifiedCache, cacheType)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	status := http.StatusCreated
+	ifMatch := r.Header.Get("If-Match")
+	switch {
+	case r.Method == http.MethodPost || r.Header.Get("If-None-Match") == "*":
+		err = cache.Add(c, key, value, e.ttl)
+	case ifMatch != "":
+		status = http.StatusOK
+		err = cache.Update(c, key, func(current interface{}) (interface{}, time.Duration, error) {
+			if !etagMatches(ifMatch, current) {
+				return nil, 0, errPreconditionFailed
+			}
+			return value, e.ttl, nil
+		})
+		if cache.IsCacheMiss(err) {
+			err = errPreconditionFailed
+		}
+	default:
+		err = cache.Add(c, key, value, e.ttl)
+		if errors.Is(err, cache.ErrKeyExists) {
+			status = http.StatusOK
+			err = c.Set(key, value, e.ttl)
+		}
+	}
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+	writeWritten(w, r, status, key, cacheType, value, e)
+}
+
+// handlePatch applies a JSON Merge Patch (RFC 7386) to a stored JSON
+// document in one atomic update of the backend. The entry keeps its expiry
+// unless the ttl or expires_at query parameter sets a new one.
+func handlePatch(w http.ResponseWriter, r *http.Request, unifiedCache *UnifiedCache, key, cacheType string) {
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != mergePatchContentType && mediaType != jsonContentType {
+		http.Error(w, fmt.Sprintf("patches must be %s", mergePatchContentType), http.StatusUnsupportedMediaType)
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxValueSize))
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		http.Error(w, errValueTooLarge.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+	patch, err := decodeJSON(body)
+	if err != nil {
+		http.Error(w, "Invalid merge patch", http.StatusBadRequest)
+		return
+	}
+	query := r.URL.Query()
+	keepExpiry := query.Get("ttl") == "" && query.Get("expires_at") == ""
+	e, err := unifiedCache.requestExpiry(r, nil, nil, cacheType)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	c, err := cacheByType(unifiedCache, cacheType)
+	if err != nil {
+		writeError(w, err, http.StatusNotFound)
+		return
+	}
+
+	ifMatch := r.Header.Get("If-Match")
+	var patched cache.TypedValue
+	err = cache.Update(c, key, func(current interface{}) (interface{}, time.Duration, error) {
+		if ifMatch != "" && !etagMatches(ifMatch, current) {
+			return nil, 0, errPreconditionFailed
+		}
+		typed, ok := current.(cache.TypedValue)
+		if mediaType, _, _ := mime.ParseMediaType(typed.ContentType); !ok || mediaType != jsonContentType {
+			return nil, 0, errNotJSONDocument
+		}
+		if typed.Expired(time.Now()) {
+			return nil, 0, cache.ErrCacheMiss
+		}
+		document, err := decodeJSON(typed.Data)
+		if err != nil {
+			return nil, 0, errNotJSONDocument
+		}
+		data, err := json.Marshal(mergePatch(document, patch))
+		if err != nil {
+			return nil, 0, err
+		}
+		if keepExpiry {
+			e = expiry{expiresAt: typed.ExpiresAt}
+			if !typed.ExpiresAt.IsZero() {
+				e.ttl = time.Until(typed.ExpiresAt)
+			}
+		}
+		patched = cache.TypedValue{ContentType: typed.ContentType, Data: data, ModifiedAt: time.Now(), ExpiresAt: e.expiresAt}
+		return patched, e.ttl, nil
+	})
+	if err != nil {
+		writeError(w, err, http.StatusNotFound)
+		return
+	}
+	writeWritten(w, r, http.StatusOK, key, cacheType, patched, e)
+}
+
+// writeWritten answers a successful write with the new ETag, the location
+// of a created entry and the chosen expiry.
+func writeWritten(w http.ResponseWriter, r *http.Request, status int, key, cacheType string, value interface{}, e expiry) {
+	if body, contentType, err := valueBody(value); err == nil {
+		w.Header().Set("ETag", entityTag(contentType, body))
+	}
+	if status == http.StatusCreated {
+		location := url.URL{Path: r.URL.Path}
+		if cacheType != "" {
+			location.RawQuery = url.Values{"cache": {cacheType}}.Encode()
+		}
+		w.Header().Set("Location", location.String())
+	}
+	writeJSON(w, status, newWriteResponse(key, cacheType, e))
+}
+
+// etagMatches reports whether an If-Match header matches the ETag of value.
+func etagMatches(header string, value interface{}) bool {
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+	body, contentType, err := valueBody(value)
+	if err != nil {
+		return false
+	}
+	etag := entityTag(contentType, body)
+	for _, candidate := range strings.Split(header, ",") {
+		if strings.TrimSpace(candidate) == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeJSON keeps numbers as json.Number, so patching does not round them.
+func decodeJSON(data []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	if decoder.More() {
+		return nil, errors.New("trailing data after JSON value")
+	}
+	return value, nil
+}
+
+// mergePatch applies patch to target as RFC 7386 describes: objects are
+// merged member by member, null removes a member and anything else
+// replaces the target.
+func mergePatch(target, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = make(map[string]interface{})
+	}
+	for name, value := range patchObject {
+		if value == nil {
+			delete(targetObject, name)
+			continue
+		}
+		targetObject[name] = mergePatch(targetObject[name], value)
+	}
+	return targetObject
+}
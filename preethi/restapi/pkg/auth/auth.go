@@ -0,0 +1,322 @@
+//API keys and signed bearer tokens, and what they may access
+
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNoCredentials = errors.New("no API key or bearer token")
+	ErrUnknownKey    = errors.New("unknown API key")
+	ErrInvalidToken  = errors.New("invalid bearer token")
+	ErrTokenExpired  = errors.New("bearer token has expired")
+)
+
+// minSecretSize is the shortest token secret accepted, the size of the
+// HMAC-SHA256 output.
+const minSecretSize = 32
+
+type Role string
+
+const (
+	RoleRead   Role = "read"
+	RoleWrite  Role = "write"
+	RoleDelete Role = "delete"
+	// RoleAdmin includes the other roles, and allows issuing tokens within
+	// its scope.
+	RoleAdmin Role = "admin"
+)
+
+func ParseRole(value string) (Role, error) {
+	switch role := Role(value); role {
+	case RoleRead, RoleWrite, RoleDelete, RoleAdmin:
+		return role, nil
+	}
+	return "", fmt.Errorf("unknown role %q, expected read, write, delete or admin", value)
+}
+
+// Grant gives roles on the keys starting with Prefix in Backend. An empty
+// Backend stands for every backend and an empty Prefix for every key.
+type Grant struct {
+	Backend string `json:"backend,omitempty"`
+	Prefix  string `json:"prefix,omitempty"`
+	Roles   []Role `json:"roles"`
+}
+
+func (g Grant) validate() error {
+	if len(g.Roles) == 0 {
+		return fmt.Errorf("grant on %q has no roles", g.Backend+"/"+g.Prefix)
+	}
+	for _, role := range g.Roles {
+		if _, err := ParseRole(string(role)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// covers reports whether g gives role on every key starting with prefix in backend.
+func (g Grant) covers(role Role, backend, prefix string) bool {
+	if g.Backend != "" && g.Backend != backend {
+		return false
+	}
+	if !strings.HasPrefix(prefix, g.Prefix) {
+		return false
+	}
+	for _, granted := range g.Roles {
+		if granted == role || granted == RoleAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// Principal is an authenticated client: the id of its API key, or the
+// subject of its token. Issuer is the id of the API key a token was issued
+// under, directly or through other tokens.
+type Principal struct {
+	Subject string  `json:"sub"`
+	Issuer  string  `json:"iss,omitempty"`
+	Grants  []Grant `json:"grants"`
+}
+
+// IssuingKey is the id of the API key behind p: the key it authenticated
+// with, or the one its token was issued under.
+func (p *Principal) IssuingKey() string {
+	if p.Issuer != "" {
+		return p.Issuer
+	}
+	return p.Subject
+}
+
+// Allows reports whether p has role on every key starting with prefix in
+// backend; a single key is its own prefix. An empty backend asks for every
+// backend.
+func (p *Principal) Allows(role Role, backend, prefix string) bool {
+	for _, grant := range p.Grants {
+		if grant.covers(role, backend, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+type principalKey struct{}
+
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFrom returns the client authenticated for a request, if any.
+func PrincipalFrom(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok
+}
+
+// Authenticator checks the static API keys and the bearer tokens signed
+// with its secret. Tokens are verified locally, so every instance sharing
+// the secret accepts them.
+type Authenticator struct {
+	// keys maps the SHA-256 of each API key to its principal.
+	keys   map[[sha256.Size]byte]*Principal
+	secret []byte
+}
+
+type authFile struct {
+	TokenSecret string `json:"token_secret"`
+	Keys        map[string]struct {
+		Key    string  `json:"key"`
+		Grants []Grant `json:"grants"`
+	} `json:"keys"`
+}
+
+// NewAuthenticator accepts the API keys, mapped to what they may access,
+// and the secret tokens are signed with. Without a secret only API keys
+// are accepted.
+func NewAuthenticator(secret []byte, keys map[string]Principal) (*Authenticator, error) {
+	if secret != nil && len(secret) < minSecretSize {
+		return nil, fmt.Errorf("token secret must be at least %d bytes", minSecretSize)
+	}
+	a := &Authenticator{keys: make(map[[sha256.Size]byte]*Principal), secret: secret}
+	for key, principal := range keys {
+		if key == "" {
+			return nil, fmt.Errorf("API key of %q is empty", principal.Subject)
+		}
+		for _, grant := range principal.Grants {
+			if err := grant.validate(); err != nil {
+				return nil, fmt.Errorf("API key %q: %w", principal.Subject, err)
+			}
+		}
+		principal := principal
+		a.keys[sha256.Sum256([]byte(key))] = &principal
+	}
+	return a, nil
+}
+
+// LoadAuthenticator reads a JSON file of the form
+// {"token_secret": "<base64>", "keys": {"ops": {"key": "<API key>", "grants": [{"roles": ["admin"]}]},
+// "web": {"key": "<API key>", "grants": [{"backend": "redis", "prefix": "session:", "roles": ["read", "write"]}]}}}.
+func LoadAuthenticator(path string) (*Authenticator, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file authFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid auth file %s: %w", path, err)
+	}
+	var secret []byte
+	if file.TokenSecret != "" {
+		secret, err = base64.StdEncoding.DecodeString(file.TokenSecret)
+		if err != nil {
+			return nil, fmt.Errorf("token_secret in %s is not valid base64: %w", path, err)
+		}
+	}
+	keys := make(map[string]Principal)
+	for id, entry := range file.Keys {
+		if _, found := keys[entry.Key]; found {
+			return nil, fmt.Errorf("API key of %q in %s is used twice", id, path)
+		}
+		keys[entry.Key] = Principal{Subject: id, Grants: entry.Grants}
+	}
+	a, err := NewAuthenticator(secret, keys)
+	if err != nil {
+		return nil, fmt.Errorf("auth file %s: %w", path, err)
+	}
+	return a, nil
+}
+
+// CanIssue reports whether tokens can be issued and verified.
+func (a *Authenticator) CanIssue() bool {
+	return a.secret != nil
+}
+
+// Authenticate finds the client of r from an X-API-Key header, or an
+// Authorization header with a bearer token or API key.
+func (a *Authenticator) Authenticate(r *http.Request) (*Principal, error) {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return a.lookupKey(key)
+	}
+	scheme, credentials, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !strings.EqualFold(scheme, "Bearer") || credentials == "" {
+		return nil, ErrNoCredentials
+	}
+	credentials = strings.TrimSpace(credentials)
+	if principal, err := a.lookupKey(credentials); err == nil {
+		return principal, nil
+	}
+	return a.Verify(credentials)
+}
+
+func (a *Authenticator) lookupKey(key string) (*Principal, error) {
+	principal, found := a.keys[sha256.Sum256([]byte(key))]
+	if !found {
+		return nil, ErrUnknownKey
+	}
+	return principal, nil
+}
+
+type claims struct {
+	Principal
+	IssuedAt  int64 `json:"iat"`
+	ExpiresAt int64 `json:"exp"`
+}
+
+// Issue signs a token for p that is valid for ttl. The token is the
+// base64url JSON claims and their base64url HMAC-SHA256, joined by a dot.
+func (a *Authenticator) Issue(p Principal, ttl time.Duration) (string, time.Time, error) {
+	if !a.CanIssue() {
+		return "", time.Time{}, fmt.Errorf("no token secret is configured")
+	}
+	if ttl <= 0 {
+		return "", time.Time{}, fmt.Errorf("token ttl must be positive, got %v", ttl)
+	}
+	for _, grant := range p.Grants {
+		if err := grant.validate(); err != nil {
+			return "", time.Time{}, err
+		}
+	}
+	now := time.Now()
+	expiresAt := now.Add(ttl).Truncate(time.Second)
+	payload, err := json.Marshal(claims{Principal: p, IssuedAt: now.Unix(), ExpiresAt: expiresAt.Unix()})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(a.sign(encoded)), expiresAt, nil
+}
+
+// Verify checks the signature and expiry of token.
+func (a *Authenticator) Verify(token string) (*Principal, error) {
+	if !a.CanIssue() {
+		return nil, ErrUnknownKey
+	}
+	encoded, signature, found := strings.Cut(token, ".")
+	if !found {
+		return nil, ErrInvalidToken
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac, a.sign(encoded)) {
+		return nil, ErrInvalidToken
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	var c claims
+	if err := json.Unmarshal(payload, &c); err != nil {
+		return nil, ErrInvalidToken
+	}
+	if time.Now().Unix() >= c.ExpiresAt {
+		return nil, ErrTokenExpired
+	}
+	return &c.Principal, nil
+}
+
+func (a *Authenticator) sign(encoded string) []byte {
+	mac := hmac.New(sha256.New, a.secret)
+	mac.Write([]byte(encoded))
+	return mac.Sum(nil)
+}
+
+// Transport adds a short-lived admin token to the requests of base, so
+// instances sharing the secret can call each other's internal endpoints.
+func (a *Authenticator) Transport(subject string, base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{authenticator: a, subject: subject, base: base}
+}
+
+// peerTokenTTL is how long the tokens of internal requests are valid.
+const peerTokenTTL = time.Minute
+
+type transport struct {
+	authenticator *Authenticator
+	subject       string
+	base          http.RoundTripper
+}
+
+func (t *transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	token, _, err := t.authenticator.Issue(Principal{
+		Subject: t.subject,
+		Grants:  []Grant{{Roles: []Role{RoleAdmin}}},
+	}, peerTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+	r = r.Clone(r.Context())
+	r.Header.Set("Authorization", "Bearer "+token)
+	return t.base.RoundTrip(r)
+}
@@ -2,11 +2,138 @@
 
 package cache
 
-import "time"
+import (
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"time"
 
+	"github.com/bradfitz/gomemcache/memcache"
+	"github.com/go-redis/redis/v8"
+)
+
+var ErrCacheMiss = errors.New("cache miss")
+
+// IsCacheMiss reports whether err means the key was not found, whichever
+// backend returned it.
+func IsCacheMiss(err error) bool {
+	return errors.Is(err, ErrCacheMiss) || errors.Is(err, redis.Nil) || errors.Is(err, memcache.ErrCacheMiss)
+}
+
+// ExpiresAt is when an entry written with ttl expires. A zero ttl means the
+// entry does not expire, which is the zero time.
+func ExpiresAt(ttl time.Duration) time.Time {
+	if ttl == 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(ttl)
+}
+
+// Cache is implemented by every backend. A zero ttl stores a value that
+// does not expire.
 type Cache interface {
 	Set(key string, value interface{}, ttl time.Duration) error
 	Get(key string) (interface{}, error)
 	Delete(key string) error
 	GetAll() (map[string]interface{}, error)
 }
+
+// StatsProvider is implemented by caches that report runtime statistics.
+type StatsProvider interface {
+	Stats() map[string]interface{}
+}
+
+// Pinger is implemented by caches that can check their connection.
+type Pinger interface {
+	Ping() error
+}
+
+// Ping checks c if it is a Pinger; other caches are always reachable.
+func Ping(c Cache) error {
+	if pinger, ok := c.(Pinger); ok {
+		return pinger.Ping()
+	}
+	return nil
+}
+
+// ExpiryReporter is implemented by caches that can tell when their entries
+// expire.
+type ExpiryReporter interface {
+	// Expirations returns when each of keys expires, the zero time for
+	// entries that do not. Keys that are not found are left out.
+	Expirations(keys []string) (map[string]time.Time, error)
+}
+
+// Expirations asks c when keys expire. It returns nil when c is not an
+// ExpiryReporter, and the expirations are unknown.
+func Expirations(c Cache, keys []string) (map[string]time.Time, error) {
+	if reporter, ok := c.(ExpiryReporter); ok {
+		return reporter.Expirations(keys)
+	}
+	return nil, nil
+}
+
+// Scanner is implemented by caches that can list their entries page by page.
+// An empty cursor starts a scan and an empty next cursor means it is complete.
+type Scanner interface {
+	Scan(cursor string, match string, count int) (entries map[string]interface{}, next string, err error)
+}
+
+// Scan lists one page of c. Caches that are not Scanners are read with
+// GetAll and paged in key order. match is a glob pattern as understood by
+// path.Match.
+func Scan(c Cache, cursor string, match string, count int) (map[string]interface{}, string, error) {
+	if scanner, ok := c.(Scanner); ok {
+		return scanner.Scan(cursor, match, count)
+	}
+	all, err := c.GetAll()
+	if err != nil {
+		return nil, "", err
+	}
+	keys := make([]string, 0, len(all))
+	for key := range all {
+		if ok, err := matchKey(match, key); err != nil {
+			return nil, "", err
+		} else if ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	keys, next := pageKeys(keys, cursor, count)
+	entries := make(map[string]interface{}, len(keys))
+	for _, key := range keys {
+		entries[key] = all[key]
+	}
+	return entries, next, nil
+}
+
+// matchKey reports whether key matches the glob pattern match; an empty
+// pattern matches every key.
+func matchKey(match, key string) (bool, error) {
+	if match == "" {
+		return true, nil
+	}
+	ok, err := path.Match(match, key)
+	if err != nil {
+		return false, fmt.Errorf("invalid match pattern %q: %w", match, err)
+	}
+	return ok, nil
+}
+
+// pageKeys returns up to count of the sorted keys following cursor. The
+// cursor is the last key of the previous page, so pages stay stable while
+// keys come and go.
+func pageKeys(keys []string, cursor string, count int) ([]string, string) {
+	start := sort.SearchStrings(keys, cursor)
+	if start < len(keys) && keys[start] == cursor {
+		start++
+	}
+	keys = keys[start:]
+	next := ""
+	if count > 0 && len(keys) > count {
+		keys = keys[:count]
+		next = keys[count-1]
+	}
+	return keys, next
+}
@@ -0,0 +1,268 @@
+//Codecs to convert cached values to bytes for the remote caches
+
+package cache
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Codec markers stored next to every encoded value. Zero is reserved for
+// values written without a marker, which are read back as strings.
+const (
+	CodecString byte = 1
+	CodecBytes  byte = 2
+	CodecJSON   byte = 3
+	CodecGob    byte = 4
+	CodecTyped  byte = 5
+)
+
+// Codec converts values to and from the bytes stored by a remote cache.
+type Codec interface {
+	ID() byte
+	Encode(value interface{}) ([]byte, error)
+	Decode(data []byte) (interface{}, error)
+}
+
+type StringCodec struct{}
+
+func (StringCodec) ID() byte { return CodecString }
+
+func (StringCodec) Encode(value interface{}) ([]byte, error) {
+	switch v := value.(type) {
+	case string:
+		return []byte(v), nil
+	case []byte:
+		return v, nil
+	case fmt.Stringer:
+		return []byte(v.String()), nil
+	default:
+		return []byte(fmt.Sprint(v)), nil
+	}
+}
+
+func (StringCodec) Decode(data []byte) (interface{}, error) {
+	return string(data), nil
+}
+
+type BytesCodec struct{}
+
+func (BytesCodec) ID() byte { return CodecBytes }
+
+func (BytesCodec) Encode(value interface{}) ([]byte, error) {
+	switch v := value.(type) {
+	case []byte:
+		return v, nil
+	case string:
+		return []byte(v), nil
+	default:
+		return nil, fmt.Errorf("bytes codec cannot encode %T", value)
+	}
+}
+
+func (BytesCodec) Decode(data []byte) (interface{}, error) {
+	out := make([]byte, len(data))
+	copy(out, data)
+	return out, nil
+}
+
+// JSONCodec decodes into the generic encoding/json types, so structs come
+// back as maps and numbers as float64.
+type JSONCodec struct{}
+
+func (JSONCodec) ID() byte { return CodecJSON }
+
+func (JSONCodec) Encode(value interface{}) ([]byte, error) {
+	return json.Marshal(value)
+}
+
+func (JSONCodec) Decode(data []byte) (interface{}, error) {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// GobCodec keeps the concrete Go type of the value. Custom types have to be
+// registered with gob.Register before they are stored.
+type GobCodec struct{}
+
+func (GobCodec) ID() byte { return CodecGob }
+
+func (GobCodec) Encode(value interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&value); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (GobCodec) Decode(data []byte) (interface{}, error) {
+	var value interface{}
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// TypedValue is a value stored with its media type, such as a JSON document
+// or an image written through the API. ModifiedAt and ExpiresAt travel with
+// the value, since not every backend can report them; zero means unknown
+// and no expiry.
+type TypedValue struct {
+	ContentType string
+	Data        []byte
+	ModifiedAt  time.Time
+	ExpiresAt   time.Time
+}
+
+// Expired reports whether the value outlived its expiry, which backends
+// rounding ttls to seconds may not notice yet.
+func (v TypedValue) Expired(now time.Time) bool {
+	return !v.ExpiresAt.IsZero() && !v.ExpiresAt.After(now)
+}
+
+// MarshalJSON writes JSON values as they are, text as a string, and other
+// values as their content type and base64 data.
+func (v TypedValue) MarshalJSON() ([]byte, error) {
+	mediaType, _, _ := mime.ParseMediaType(v.ContentType)
+	switch {
+	case mediaType == "application/json" && json.Valid(v.Data):
+		return v.Data, nil
+	case mediaType == "text/plain" && utf8.Valid(v.Data):
+		return json.Marshal(string(v.Data))
+	}
+	return json.Marshal(struct {
+		ContentType string `json:"content_type"`
+		Data        []byte `json:"data"`
+	}{v.ContentType, v.Data})
+}
+
+// TypedCodec stores the content type, prefixed with its length, and the
+// modification and expiry times in Unix nanoseconds in front of the data.
+type TypedCodec struct{}
+
+func (TypedCodec) ID() byte { return CodecTyped }
+
+func (TypedCodec) Encode(value interface{}) ([]byte, error) {
+	v, ok := value.(TypedValue)
+	if !ok {
+		return nil, fmt.Errorf("typed codec cannot encode %T", value)
+	}
+	data := binary.AppendUvarint(nil, uint64(len(v.ContentType)))
+	data = append(data, v.ContentType...)
+	data = binary.AppendVarint(data, unixNano(v.ModifiedAt))
+	data = binary.AppendVarint(data, unixNano(v.ExpiresAt))
+	return append(data, v.Data...), nil
+}
+
+func (TypedCodec) Decode(data []byte) (interface{}, error) {
+	errTruncated := fmt.Errorf("typed value is truncated")
+	length, n := binary.Uvarint(data)
+	if n <= 0 || uint64(len(data)-n) < length {
+		return nil, errTruncated
+	}
+	v := TypedValue{ContentType: string(data[n : n+int(length)])}
+	data = data[n+int(length):]
+	for _, t := range []*time.Time{&v.ModifiedAt, &v.ExpiresAt} {
+		nanos, n := binary.Varint(data)
+		if n <= 0 {
+			return nil, errTruncated
+		}
+		*t = fromUnixNano(nanos)
+		data = data[n:]
+	}
+	v.Data = make([]byte, len(data))
+	copy(v.Data, data)
+	return v, nil
+}
+
+func unixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func fromUnixNano(nanos int64) time.Time {
+	if nanos == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, nanos)
+}
+
+var (
+	codecsMutex sync.RWMutex
+	codecs      = map[byte]Codec{
+		CodecString: StringCodec{},
+		CodecBytes:  BytesCodec{},
+		CodecJSON:   JSONCodec{},
+		CodecGob:    GobCodec{},
+		CodecTyped:  TypedCodec{},
+	}
+)
+
+// RegisterCodec makes a custom codec available for decoding stored values.
+func RegisterCodec(codec Codec) error {
+	id := codec.ID()
+	if id == 0 || id > codecMask {
+		return fmt.Errorf("codec id %d out of range 1-%d", id, codecMask)
+	}
+	codecsMutex.Lock()
+	defer codecsMutex.Unlock()
+	if _, found := codecs[id]; found {
+		return fmt.Errorf("codec id %d already registered", id)
+	}
+	codecs[id] = codec
+	return nil
+}
+
+func lookupCodec(id byte) (Codec, bool) {
+	codecsMutex.RLock()
+	defer codecsMutex.RUnlock()
+	codec, found := codecs[id]
+	return codec, found
+}
+
+// codecMask selects the codec id from a value marker.
+const codecMask byte = 0x07
+
+// EncodeValue stores strings and byte slices as they are, typed values with
+// their content type and everything else with the configured codec,
+// returning the marker needed to decode it.
+func EncodeValue(codec Codec, value interface{}) ([]byte, byte, error) {
+	switch value.(type) {
+	case string:
+		codec = StringCodec{}
+	case []byte:
+		codec = BytesCodec{}
+	case TypedValue:
+		codec = TypedCodec{}
+	}
+	data, err := codec.Encode(value)
+	if err != nil {
+		return nil, 0, err
+	}
+	return data, codec.ID(), nil
+}
+
+// DecodeValue reverses EncodeValue.
+func DecodeValue(data []byte, marker byte) (interface{}, error) {
+	id := marker & codecMask
+	if id == 0 {
+		return string(data), nil
+	}
+	codec, found := lookupCodec(id)
+	if !found {
+		return nil, fmt.Errorf("unknown codec id %d", id)
+	}
+	return codec.Decode(data)
+}
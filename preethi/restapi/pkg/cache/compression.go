@@ -0,0 +1,139 @@
+//Compression of large values stored in the remote caches
+
+package cache
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"sync"
+)
+
+type Compression byte
+
+const (
+	CompressionNone  Compression = 0
+	CompressionGzip  Compression = 1
+	CompressionZlib  Compression = 2
+	CompressionFlate Compression = 3
+)
+
+// The compression algorithm is kept in bits 3-4 of the value marker,
+// next to the codec id.
+const (
+	compressionShift      = 3
+	compressionMask  byte = 0x18
+)
+
+func (c Compression) String() string {
+	switch c {
+	case CompressionNone:
+		return "none"
+	case CompressionGzip:
+		return "gzip"
+	case CompressionZlib:
+		return "zlib"
+	case CompressionFlate:
+		return "flate"
+	default:
+		return fmt.Sprintf("compression(%d)", byte(c))
+	}
+}
+
+// ParseCompression converts an algorithm name such as "gzip" to a Compression.
+func ParseCompression(name string) (Compression, error) {
+	for _, c := range []Compression{CompressionNone, CompressionGzip, CompressionZlib, CompressionFlate} {
+		if c.String() == name {
+			return c, nil
+		}
+	}
+	if name == "" {
+		return CompressionNone, nil
+	}
+	return CompressionNone, fmt.Errorf("unknown compression %q", name)
+}
+
+func compress(algorithm Compression, data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	var err error
+	switch algorithm {
+	case CompressionGzip:
+		w = gzip.NewWriter(&buf)
+	case CompressionZlib:
+		w = zlib.NewWriter(&buf)
+	case CompressionFlate:
+		w, err = flate.NewWriter(&buf, flate.DefaultCompression)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown compression %d", algorithm)
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decompress(algorithm Compression, data []byte) ([]byte, error) {
+	var r io.ReadCloser
+	var err error
+	switch algorithm {
+	case CompressionGzip:
+		r, err = gzip.NewReader(bytes.NewReader(data))
+	case CompressionZlib:
+		r, err = zlib.NewReader(bytes.NewReader(data))
+	case CompressionFlate:
+		r = flate.NewReader(bytes.NewReader(data))
+	default:
+		return nil, fmt.Errorf("unknown compression %d", algorithm)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+type compressionStats struct {
+	mutex        sync.Mutex
+	compressed   int64
+	uncompressed int64
+	bytesBefore  int64
+	bytesAfter   int64
+}
+
+func (s *compressionStats) record(before, after int, compressed bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if !compressed {
+		s.uncompressed++
+		return
+	}
+	s.compressed++
+	s.bytesBefore += int64(before)
+	s.bytesAfter += int64(after)
+}
+
+func (s *compressionStats) snapshot() map[string]interface{} {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	ratio := 0.0
+	if s.bytesAfter > 0 {
+		ratio = float64(s.bytesBefore) / float64(s.bytesAfter)
+	}
+	return map[string]interface{}{
+		"compressed_values":   s.compressed,
+		"uncompressed_values": s.uncompressed,
+		"bytes_before":        s.bytesBefore,
+		"bytes_after":         s.bytesAfter,
+		"ratio":               ratio,
+	}
+}
@@ -0,0 +1,261 @@
+//Encrypting decorator so remote caches only store ciphertext
+
+package cache
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// encryptionVersion is the first byte of every ciphertext written by EncryptedCache.
+const encryptionVersion byte = 1
+
+var ErrUnknownKeyID = errors.New("unknown encryption key id")
+
+// KeyRing holds the AES keys by id. New values are encrypted with the active
+// key, older keys are kept so values written before a rotation can be read.
+type KeyRing struct {
+	active string
+	aeads  map[string]cipher.AEAD
+}
+
+type keyFile struct {
+	Active string            `json:"active"`
+	Keys   map[string]string `json:"keys"`
+}
+
+func NewKeyRing(active string, keys map[string][]byte) (*KeyRing, error) {
+	if _, found := keys[active]; !found {
+		return nil, fmt.Errorf("active key %q is not in the key ring", active)
+	}
+	ring := &KeyRing{
+		active: active,
+		aeads:  make(map[string]cipher.AEAD),
+	}
+	for id, key := range keys {
+		if id == "" || len(id) > 255 {
+			return nil, fmt.Errorf("invalid key id %q", id)
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", id, err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", id, err)
+		}
+		ring.aeads[id] = aead
+	}
+	return ring, nil
+}
+
+// LoadKeyRing reads a JSON key file of the form
+// {"active": "k2", "keys": {"k1": "<base64 key>", "k2": "<base64 key>"}}.
+func LoadKeyRing(path string) (*KeyRing, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file keyFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid key file %s: %w", path, err)
+	}
+	keys := make(map[string][]byte)
+	for id, encoded := range file.Keys {
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("key %q in %s is not valid base64: %w", id, path, err)
+		}
+		keys[id] = key
+	}
+	return NewKeyRing(file.Active, keys)
+}
+
+func (k *KeyRing) ActiveKeyID() string {
+	return k.active
+}
+
+// seal encrypts plaintext with the active key. The cache key is used as
+// additional data so a ciphertext cannot be moved to another key.
+func (k *KeyRing) seal(key string, plaintext []byte) ([]byte, error) {
+	aead := k.aeads[k.active]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	out := make([]byte, 0, 2+len(k.active)+len(nonce)+len(plaintext)+aead.Overhead())
+	out = append(out, encryptionVersion, byte(len(k.active)))
+	out = append(out, k.active...)
+	out = append(out, nonce...)
+	return aead.Seal(out, nonce, plaintext, []byte(key)), nil
+}
+
+func (k *KeyRing) open(key string, ciphertext []byte) ([]byte, error) {
+	if len(ciphertext) < 2 || ciphertext[0] != encryptionVersion {
+		return nil, errors.New("value is not encrypted")
+	}
+	idLen := int(ciphertext[1])
+	if len(ciphertext) < 2+idLen {
+		return nil, errors.New("truncated ciphertext")
+	}
+	id := string(ciphertext[2 : 2+idLen])
+	aead, found := k.aeads[id]
+	if !found {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKeyID, id)
+	}
+	rest := ciphertext[2+idLen:]
+	if len(rest) < aead.NonceSize() {
+		return nil, errors.New("truncated ciphertext")
+	}
+	return aead.Open(nil, rest[:aead.NonceSize()], rest[aead.NonceSize():], []byte(key))
+}
+
+// EncryptedCache encrypts values with AES-GCM before handing them to the
+// wrapped cache and decrypts them again on the way out.
+type EncryptedCache struct {
+	cache Cache
+	keys  *KeyRing
+	opts  options
+}
+
+func NewEncryptedCache(inner Cache, keys *KeyRing, opts ...Option) *EncryptedCache {
+	return &EncryptedCache{
+		cache: inner,
+		keys:  keys,
+		opts:  newOptions(opts),
+	}
+}
+
+func (c *EncryptedCache) Set(key string, value interface{}, ttl time.Duration) error {
+	ciphertext, err := c.encrypt(key, value)
+	if err != nil {
+		return err
+	}
+	return c.cache.Set(key, ciphertext, ttl)
+}
+
+func (c *EncryptedCache) Add(key string, value interface{}, ttl time.Duration) error {
+	ciphertext, err := c.encrypt(key, value)
+	if err != nil {
+		return err
+	}
+	return Add(c.cache, key, ciphertext, ttl)
+}
+
+// Update decrypts the current value for fn and encrypts its result, so the
+// inner cache updates the ciphertext as atomically as it can.
+func (c *EncryptedCache) Update(key string, fn UpdateFunc) error {
+	return Update(c.cache, key, func(current interface{}) (interface{}, time.Duration, error) {
+		plain, err := c.decrypt(key, current)
+		if err != nil {
+			return nil, 0, err
+		}
+		next, ttl, err := fn(plain)
+		if err != nil {
+			return nil, 0, err
+		}
+		ciphertext, err := c.encrypt(key, next)
+		return ciphertext, ttl, err
+	})
+}
+
+func (c *EncryptedCache) Get(key string) (interface{}, error) {
+	value, err := c.cache.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	return c.decrypt(key, value)
+}
+
+func (c *EncryptedCache) Delete(key string) error {
+	return c.cache.Delete(key)
+}
+
+func (c *EncryptedCache) GetAll() (map[string]interface{}, error) {
+	entries, err := c.cache.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	allItems := make(map[string]interface{}, len(entries))
+	for key, value := range entries {
+		plain, err := c.decrypt(key, value)
+		if err != nil {
+			// Entries sealed with a retired key or written unencrypted
+			// must not fail the whole listing; Get still reports them.
+			continue
+		}
+		allItems[key] = plain
+	}
+	return allItems, nil
+}
+
+func (c *EncryptedCache) Scan(cursor string, match string, count int) (map[string]interface{}, string, error) {
+	entries, next, err := Scan(c.cache, cursor, match, count)
+	if err != nil {
+		return nil, "", err
+	}
+	for key, value := range entries {
+		plain, err := c.decrypt(key, value)
+		if err != nil {
+			delete(entries, key)
+			continue
+		}
+		entries[key] = plain
+	}
+	return entries, next, nil
+}
+
+func (c *EncryptedCache) Expirations(keys []string) (map[string]time.Time, error) {
+	return Expirations(c.cache, keys)
+}
+
+func (c *EncryptedCache) Ping() error {
+	return Ping(c.cache)
+}
+
+func (c *EncryptedCache) Stats() map[string]interface{} {
+	stats := map[string]interface{}{
+		"encryption_key_id": c.keys.ActiveKeyID(),
+	}
+	if provider, ok := c.cache.(StatsProvider); ok {
+		for k, v := range provider.Stats() {
+			stats[k] = v
+		}
+	}
+	return stats
+}
+
+func (c *EncryptedCache) encrypt(key string, value interface{}) ([]byte, error) {
+	data, marker, err := c.opts.encode(value)
+	if err != nil {
+		return nil, err
+	}
+	return c.keys.seal(key, append([]byte{marker}, data...))
+}
+
+func (c *EncryptedCache) decrypt(key string, value interface{}) (interface{}, error) {
+	var ciphertext []byte
+	switch v := value.(type) {
+	case []byte:
+		ciphertext = v
+	case string:
+		ciphertext = []byte(v)
+	default:
+		return nil, fmt.Errorf("unexpected encrypted value type %T", value)
+	}
+	plaintext, err := c.keys.open(key, ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	if len(plaintext) == 0 {
+		return nil, errors.New("empty plaintext")
+	}
+	return c.opts.decode(plaintext[1:], plaintext[0])
+}
@@ -0,0 +1,95 @@
+//Ketama style consistent hash ring to spread keys over several nodes
+
+package cache
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"sort"
+	"strconv"
+)
+
+// pointsPerWeight is the number of ring points a node gets per unit of
+// weight, the same 160 points per server used by ketama.
+const pointsPerWeight = 160
+
+type ringPoint struct {
+	hash uint32
+	node string
+}
+
+// HashRing maps keys to nodes so that adding or removing one of N nodes
+// only moves about 1/N of the keys.
+type HashRing struct {
+	points []ringPoint
+	nodes  []string
+}
+
+// NewHashRing builds a ring from node names and their weights. Nodes with a
+// weight below one get a weight of one.
+func NewHashRing(weights map[string]int) *HashRing {
+	r := &HashRing{}
+	for node, weight := range weights {
+		if weight < 1 {
+			weight = 1
+		}
+		r.nodes = append(r.nodes, node)
+		// Every md5 sum yields four points on the ring.
+		for i := 0; i < weight*pointsPerWeight/4; i++ {
+			sum := md5.Sum([]byte(node + "-" + strconv.Itoa(i)))
+			for j := 0; j < 4; j++ {
+				r.points = append(r.points, ringPoint{
+					hash: binary.LittleEndian.Uint32(sum[j*4:]),
+					node: node,
+				})
+			}
+		}
+	}
+	sort.Strings(r.nodes)
+	sort.Slice(r.points, func(i, j int) bool {
+		if r.points[i].hash == r.points[j].hash {
+			return r.points[i].node < r.points[j].node
+		}
+		return r.points[i].hash < r.points[j].hash
+	})
+	return r
+}
+
+func (r *HashRing) Nodes() []string {
+	return append([]string(nil), r.nodes...)
+}
+
+// Get returns the node owning key.
+func (r *HashRing) Get(key string) (string, bool) {
+	var owner string
+	r.Walk(key, func(node string) bool {
+		owner = node
+		return false
+	})
+	return owner, owner != ""
+}
+
+// Walk calls fn with every distinct node in ring order starting at the
+// owner of key, until fn returns false. It is used to skip nodes that are
+// down without remapping the keys of the healthy ones.
+func (r *HashRing) Walk(key string, fn func(node string) bool) {
+	if len(r.points) == 0 {
+		return
+	}
+	sum := md5.Sum([]byte(key))
+	hash := binary.LittleEndian.Uint32(sum[:4])
+	start := sort.Search(len(r.points), func(i int) bool {
+		return r.points[i].hash >= hash
+	})
+	seen := make(map[string]bool, len(r.nodes))
+	for i := 0; i < len(r.points) && len(seen) < len(r.nodes); i++ {
+		point := r.points[(start+i)%len(r.points)]
+		if seen[point.node] {
+			continue
+		}
+		seen[point.node] = true
+		if !fn(point.node) {
+			return
+		}
+	}
+}
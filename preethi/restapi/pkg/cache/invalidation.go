@@ -0,0 +1,263 @@
+//Invalidation of local caches across instances over Redis pub/sub
+
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Invalidator is implemented by local caches whose entries can be dropped
+// when another instance changes them.
+type Invalidator interface {
+	Delete(key string) error
+	DeletePrefix(prefix string) int
+	Flush()
+}
+
+type InvalidationMessage struct {
+	NodeID    string `json:"node"`
+	Key       string `json:"key,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+	Flush     bool   `json:"flush,omitempty"`
+}
+
+// resubscribeDelay is how long the bus waits before receiving again after
+// the subscription failed.
+const resubscribeDelay = time.Second
+
+// InvalidationBus publishes invalidations of this node on a Redis channel
+// and applies the ones published by other nodes to the local caches.
+// Messages sent while the subscription was down are lost, so the local
+// caches are flushed completely whenever it reconnects.
+type InvalidationBus struct {
+	client  redis.UniversalClient
+	channel string
+	nodeID  string
+	locals  []Invalidator
+
+	pubsub *redis.PubSub
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	published int64
+	applied   int64
+	flushes   int64
+}
+
+func NewInvalidationBus(redisCache *RedisCache, channel, nodeID string, locals ...Invalidator) *InvalidationBus {
+	return &InvalidationBus{
+		client:  redisCache.client,
+		channel: channel,
+		nodeID:  nodeID,
+		locals:  locals,
+	}
+}
+
+// Start subscribes to the channel and applies invalidations until Close.
+func (b *InvalidationBus) Start() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	pubsub := b.client.Subscribe(ctx, b.channel)
+	// Wait for the confirmation so invalidations published right after
+	// Start are not missed.
+	if _, err := pubsub.Receive(ctx); err != nil {
+		cancel()
+		pubsub.Close()
+		return err
+	}
+	b.pubsub = pubsub
+	b.cancel = cancel
+	b.done = make(chan struct{})
+	go b.receive(ctx)
+	return nil
+}
+
+func (b *InvalidationBus) Close() error {
+	if b.pubsub == nil {
+		return nil
+	}
+	b.cancel()
+	err := b.pubsub.Close()
+	<-b.done
+	return err
+}
+
+func (b *InvalidationBus) InvalidateKey(key string) error {
+	return b.publish(InvalidationMessage{Key: key})
+}
+
+func (b *InvalidationBus) InvalidateNamespace(prefix string) error {
+	return b.publish(InvalidationMessage{Namespace: prefix})
+}
+
+func (b *InvalidationBus) InvalidateAll() error {
+	return b.publish(InvalidationMessage{Flush: true})
+}
+
+func (b *InvalidationBus) Stats() map[string]interface{} {
+	return map[string]interface{}{
+		"node_id":   b.nodeID,
+		"channel":   b.channel,
+		"published": atomic.LoadInt64(&b.published),
+		"applied":   atomic.LoadInt64(&b.applied),
+		"flushes":   atomic.LoadInt64(&b.flushes),
+	}
+}
+
+func (b *InvalidationBus) publish(message InvalidationMessage) error {
+	message.NodeID = b.nodeID
+	payload, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	if err := b.client.Publish(context.Background(), b.channel, payload).Err(); err != nil {
+		return err
+	}
+	atomic.AddInt64(&b.published, 1)
+	return nil
+}
+
+func (b *InvalidationBus) receive(ctx context.Context) {
+	defer close(b.done)
+	for {
+		msg, err := b.pubsub.Receive(ctx)
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, redis.ErrClosed) {
+				return
+			}
+			// The next Receive reconnects and subscribes again.
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(resubscribeDelay):
+			}
+			continue
+		}
+		switch m := msg.(type) {
+		case *redis.Subscription:
+			if m.Kind == "subscribe" {
+				b.flush()
+			}
+		case *redis.Message:
+			b.apply(m.Payload)
+		}
+	}
+}
+
+func (b *InvalidationBus) apply(payload string) {
+	var message InvalidationMessage
+	if err := json.Unmarshal([]byte(payload), &message); err != nil || message.NodeID == b.nodeID {
+		return
+	}
+	switch {
+	case message.Flush:
+		b.flush()
+		return
+	case message.Namespace != "":
+		for _, local := range b.locals {
+			local.DeletePrefix(message.Namespace)
+		}
+	case message.Key != "":
+		for _, local := range b.locals {
+			local.Delete(message.Key)
+		}
+	}
+	atomic.AddInt64(&b.applied, 1)
+}
+
+func (b *InvalidationBus) flush() {
+	for _, local := range b.locals {
+		local.Flush()
+	}
+	atomic.AddInt64(&b.flushes, 1)
+}
+
+// InvalidatingCache publishes an invalidation for every key written or
+// deleted through it, so other nodes drop their local copies.
+type InvalidatingCache struct {
+	cache Cache
+	bus   *InvalidationBus
+}
+
+func NewInvalidatingCache(inner Cache, bus *InvalidationBus) *InvalidatingCache {
+	return &InvalidatingCache{cache: inner, bus: bus}
+}
+
+func (c *InvalidatingCache) Set(key string, value interface{}, ttl time.Duration) error {
+	if err := c.cache.Set(key, value, ttl); err != nil {
+		return err
+	}
+	return c.bus.InvalidateKey(key)
+}
+
+func (c *InvalidatingCache) Add(key string, value interface{}, ttl time.Duration) error {
+	if err := Add(c.cache, key, value, ttl); err != nil {
+		return err
+	}
+	return c.bus.InvalidateKey(key)
+}
+
+func (c *InvalidatingCache) Update(key string, fn UpdateFunc) error {
+	if err := Update(c.cache, key, fn); err != nil {
+		return err
+	}
+	return c.bus.InvalidateKey(key)
+}
+
+func (c *InvalidatingCache) Get(key string) (interface{}, error) {
+	return c.cache.Get(key)
+}
+
+func (c *InvalidatingCache) Delete(key string) error {
+	err := c.cache.Delete(key)
+	if err != nil && !IsCacheMiss(err) {
+		return err
+	}
+	// Other nodes may still hold the key even when this one did not.
+	if pubErr := c.bus.InvalidateKey(key); pubErr != nil {
+		return pubErr
+	}
+	return err
+}
+
+// DeleteNamespace drops every key starting with prefix here and on the
+// other nodes.
+func (c *InvalidatingCache) DeleteNamespace(prefix string) error {
+	if local, ok := c.cache.(Invalidator); ok {
+		local.DeletePrefix(prefix)
+	}
+	return c.bus.InvalidateNamespace(prefix)
+}
+
+func (c *InvalidatingCache) GetAll() (map[string]interface{}, error) {
+	return c.cache.GetAll()
+}
+
+func (c *InvalidatingCache) Scan(cursor string, match string, count int) (map[string]interface{}, string, error) {
+	return Scan(c.cache, cursor, match, count)
+}
+
+func (c *InvalidatingCache) Expirations(keys []string) (map[string]time.Time, error) {
+	return Expirations(c.cache, keys)
+}
+
+func (c *InvalidatingCache) Ping() error {
+	return Ping(c.cache)
+}
+
+func (c *InvalidatingCache) Stats() map[string]interface{} {
+	stats := map[string]interface{}{
+		"invalidation": c.bus.Stats(),
+	}
+	if provider, ok := c.cache.(StatsProvider); ok {
+		for k, v := range provider.Stats() {
+			stats[k] = v
+		}
+	}
+	return stats
+}
@@ -0,0 +1,161 @@
+//Key index to enumerate the contents of memcached
+
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/bradfitz/gomemcache/memcache"
+)
+
+// KeyIndex tracks the keys written through a MemcachedCache, since
+// memcached itself cannot list its keys. Keys that memcached evicts on its
+// own stay in the index until a listing notices they are gone.
+type KeyIndex interface {
+	Add(key string) error
+	Remove(keys ...string) error
+	Keys() ([]string, error)
+}
+
+// LocalKeyIndex keeps the index in process memory, so it only knows about
+// keys written by this instance.
+type LocalKeyIndex struct {
+	keys  map[string]struct{}
+	mutex sync.Mutex
+}
+
+func NewLocalKeyIndex() *LocalKeyIndex {
+	return &LocalKeyIndex{keys: make(map[string]struct{})}
+}
+
+func (i *LocalKeyIndex) Add(key string) error {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	i.keys[key] = struct{}{}
+	return nil
+}
+
+func (i *LocalKeyIndex) Remove(keys ...string) error {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	for _, key := range keys {
+		delete(i.keys, key)
+	}
+	return nil
+}
+
+func (i *LocalKeyIndex) Keys() ([]string, error) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	keys := make([]string, 0, len(i.keys))
+	for key := range i.keys {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// maxIndexRetries bounds the compare-and-swap attempts of a shared index update.
+const maxIndexRetries = 10
+
+// sharedKeyIndex stores the index as a newline separated list under a
+// memcached key, so every instance using the same key sees the same keys.
+// Updates use compare-and-swap and the whole list has to fit in one item.
+type sharedKeyIndex struct {
+	client   *memcache.Client
+	indexKey string
+}
+
+func (i *sharedKeyIndex) Add(key string) error {
+	return i.update(func(keys map[string]struct{}) bool {
+		if _, found := keys[key]; found {
+			return false
+		}
+		keys[key] = struct{}{}
+		return true
+	})
+}
+
+func (i *sharedKeyIndex) Remove(keys ...string) error {
+	return i.update(func(indexed map[string]struct{}) bool {
+		changed := false
+		for _, key := range keys {
+			if _, found := indexed[key]; found {
+				delete(indexed, key)
+				changed = true
+			}
+		}
+		return changed
+	})
+}
+
+func (i *sharedKeyIndex) Keys() ([]string, error) {
+	item, err := i.client.Get(i.indexKey)
+	if errors.Is(err, memcache.ErrCacheMiss) {
+		return []string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0)
+	for key := range parseKeyIndex(item.Value) {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func (i *sharedKeyIndex) update(change func(map[string]struct{}) bool) error {
+	for attempt := 0; attempt < maxIndexRetries; attempt++ {
+		item, err := i.client.Get(i.indexKey)
+		if errors.Is(err, memcache.ErrCacheMiss) {
+			keys := make(map[string]struct{})
+			if !change(keys) {
+				return nil
+			}
+			err = i.client.Add(&memcache.Item{Key: i.indexKey, Value: formatKeyIndex(keys)})
+			if errors.Is(err, memcache.ErrNotStored) {
+				// Another instance created the index first.
+				continue
+			}
+			return err
+		}
+		if err != nil {
+			return err
+		}
+		keys := parseKeyIndex(item.Value)
+		if !change(keys) {
+			return nil
+		}
+		item.Value = formatKeyIndex(keys)
+		err = i.client.CompareAndSwap(item)
+		if errors.Is(err, memcache.ErrCASConflict) || errors.Is(err, memcache.ErrNotStored) {
+			continue
+		}
+		return err
+	}
+	return fmt.Errorf("failed to update key index %s after %d attempts", i.indexKey, maxIndexRetries)
+}
+
+func parseKeyIndex(data []byte) map[string]struct{} {
+	keys := make(map[string]struct{})
+	for _, key := range strings.Split(string(data), "\n") {
+		if key != "" {
+			keys[key] = struct{}{}
+		}
+	}
+	return keys
+}
+
+func formatKeyIndex(keys map[string]struct{}) []byte {
+	var b strings.Builder
+	for key := range keys {
+		b.WriteString(key)
+		b.WriteByte('\n')
+	}
+	return []byte(b.String())
+}
@@ -4,7 +4,8 @@ package cache
 
 import (
 	"container/list"
-	"errors"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 )
@@ -15,6 +16,22 @@ type CacheItem struct {
 	expiration time.Time
 }
 
+func (i CacheItem) Key() string {
+	return i.key
+}
+
+func (i CacheItem) Value() interface{} {
+	return i.value
+}
+
+func (i CacheItem) Expiration() time.Time {
+	return i.expiration
+}
+
+func (i CacheItem) expired(now time.Time) bool {
+	return !i.expiration.IsZero() && !i.expiration.After(now)
+}
+
 type LRUCache struct {
 	capacity int
 	items    map[string]*list.Element
@@ -33,12 +50,41 @@ func NewLRUCache(capacity int) *LRUCache {
 func (c *LRUCache) Set(key string, value interface{}, ttl time.Duration) error {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
+	c.set(key, value, ttl)
+	return nil
+}
+
+func (c *LRUCache) Add(key string, value interface{}, ttl time.Duration) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if _, err := c.get(key); err == nil {
+		return ErrKeyExists
+	}
+	c.set(key, value, ttl)
+	return nil
+}
+
+func (c *LRUCache) Update(key string, fn UpdateFunc) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	item, err := c.get(key)
+	if err != nil {
+		return err
+	}
+	next, ttl, err := fn(item.value)
+	if err != nil {
+		return err
+	}
+	c.set(key, next, ttl)
+	return nil
+}
 
+func (c *LRUCache) set(key string, value interface{}, ttl time.Duration) {
 	if element, found := c.items[key]; found {
 		c.list.MoveToFront(element)
 		element.Value.(*CacheItem).value = value
-		element.Value.(*CacheItem).expiration = time.Now().Add(ttl)
-		return nil
+		element.Value.(*CacheItem).expiration = ExpiresAt(ttl)
+		return
 	}
 
 	if c.list.Len() >= c.capacity {
@@ -48,27 +94,55 @@ func (c *LRUCache) Set(key string, value interface{}, ttl time.Duration) error {
 	item := &CacheItem{
 		key:        key,
 		value:      value,
-		expiration: time.Now().Add(ttl),
+		expiration: ExpiresAt(ttl),
 	}
 	element := c.list.PushFront(item)
 	c.items[key] = element
-	return nil
 }
 
 func (c *LRUCache) Get(key string) (interface{}, error) {
+	value, _, err := c.GetWithExpiration(key)
+	return value, err
+}
+
+func (c *LRUCache) GetWithExpiration(key string) (interface{}, time.Time, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	item, err := c.get(key)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	return item.value, item.expiration, nil
+}
+
+// Expirations does not count as a use of the keys.
+func (c *LRUCache) Expirations(keys []string) (map[string]time.Time, error) {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
+	now := time.Now()
+	expirations := make(map[string]time.Time, len(keys))
+	for _, key := range keys {
+		if element, found := c.items[key]; found {
+			if item := element.Value.(*CacheItem); !item.expired(now) {
+				expirations[key] = item.expiration
+			}
+		}
+	}
+	return expirations, nil
+}
+
+// get returns the live item for key, dropping it if it expired.
+func (c *LRUCache) get(key string) (*CacheItem, error) {
 	if element, found := c.items[key]; found {
-		if element.Value.(*CacheItem).expiration.After(time.Now()) {
+		if !element.Value.(*CacheItem).expired(time.Now()) {
 			c.list.MoveToFront(element)
-			return element.Value.(*CacheItem).value, nil
+			return element.Value.(*CacheItem), nil
 		}
 		c.list.Remove(element)
 		delete(c.items, key)
-		return nil, errors.New("cache miss")
 	}
-	return nil, errors.New("cache miss")
+	return nil, ErrCacheMiss
 }
 
 func (c *LRUCache) Delete(key string) error {
@@ -80,7 +154,30 @@ func (c *LRUCache) Delete(key string) error {
 		delete(c.items, key)
 		return nil
 	}
-	return errors.New("cache miss")
+	return ErrCacheMiss
+}
+
+func (c *LRUCache) DeletePrefix(prefix string) int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	deleted := 0
+	for key, element := range c.items {
+		if strings.HasPrefix(key, prefix) {
+			c.list.Remove(element)
+			delete(c.items, key)
+			deleted++
+		}
+	}
+	return deleted
+}
+
+func (c *LRUCache) Flush() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.items = make(map[string]*list.Element)
+	c.list.Init()
 }
 
 func (c *LRUCache) GetAll() (map[string]interface{}, error) {
@@ -89,13 +186,90 @@ func (c *LRUCache) GetAll() (map[string]interface{}, error) {
 
 	allItems := make(map[string]interface{})
 	for key, element := range c.items {
-		if element.Value.(*CacheItem).expiration.After(time.Now()) {
+		if !element.Value.(*CacheItem).expired(time.Now()) {
 			allItems[key] = element.Value.(*CacheItem).value
 		}
 	}
 	return allItems, nil
 }
 
+// Scan pages the unexpired entries in key order, copying only the values
+// of the page. It does not count as a use of the keys.
+func (c *LRUCache) Scan(cursor string, match string, count int) (map[string]interface{}, string, error) {
+	items, next, err := c.ScanItems(cursor, match, count)
+	if err != nil {
+		return nil, "", err
+	}
+	entries := make(map[string]interface{}, len(items))
+	for _, item := range items {
+		entries[item.key] = item.value
+	}
+	return entries, next, nil
+}
+
+// ScanItems is Scan returning copies of the items, with their expiration.
+func (c *LRUCache) ScanItems(cursor string, match string, count int) ([]CacheItem, string, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := time.Now()
+	keys := make([]string, 0, len(c.items))
+	for key, element := range c.items {
+		if cursor != "" && key <= cursor || element.Value.(*CacheItem).expired(now) {
+			continue
+		}
+		if ok, err := matchKey(match, key); err != nil {
+			return nil, "", err
+		} else if ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	keys, next := pageKeys(keys, cursor, count)
+	items := make([]CacheItem, len(keys))
+	for i, key := range keys {
+		items[i] = *c.items[key].Value.(*CacheItem)
+	}
+	return items, next, nil
+}
+
+// Items returns copies of the unexpired items, most recently used first.
+func (c *LRUCache) Items() []CacheItem {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := time.Now()
+	items := make([]CacheItem, 0, c.list.Len())
+	for element := c.list.Front(); element != nil; element = element.Next() {
+		if item := element.Value.(*CacheItem); !item.expired(now) {
+			items = append(items, *item)
+		}
+	}
+	return items
+}
+
+// Resize changes the capacity, evicting the least recently used entries
+// when the cache holds more than the new capacity.
+func (c *LRUCache) Resize(capacity int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.capacity = capacity
+	for c.list.Len() > c.capacity {
+		c.evict()
+	}
+}
+
+func (c *LRUCache) Stats() map[string]interface{} {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return map[string]interface{}{
+		"entries":  c.list.Len(),
+		"capacity": c.capacity,
+	}
+}
+
 func (c *LRUCache) evict() {
 	if element := c.list.Back(); element != nil {
 		c.list.Remove(element)
@@ -3,6 +3,10 @@
 package cache
 
 import (
+	"errors"
+	"fmt"
+	"math"
+	"path"
 	"time"
 
 	"github.com/bradfitz/gomemcache/memcache"
@@ -10,38 +14,270 @@ import (
 
 type MemcachedCache struct {
 	client *memcache.Client
+	// probe pings the servers through the selector, keeping its
+	// connections between health checks.
+	probe    *memcache.Client
+	selector *ringSelector
+	opts     options
 }
 
-func NewMemcachedCache(address string) (*MemcachedCache, error) {
-	client := memcache.New(address)
-	if err := client.Ping(); err != nil {
+func NewMemcachedCache(address string, opts ...Option) (*MemcachedCache, error) {
+	return NewMemcachedCacheWithServers([]MemcachedServer{{Addr: address, Weight: 1}}, opts...)
+}
+
+// NewMemcachedCacheWithServers spreads keys over the servers with a
+// consistent hash ring. Servers that do not answer are skipped for the
+// configured down period once they failed the configured number of times in
+// a row, and their keys go to the next server on the ring.
+func NewMemcachedCacheWithServers(servers []MemcachedServer, opts ...Option) (*MemcachedCache, error) {
+	o := newOptions(opts)
+	selector, err := newRingSelector(servers, o.serverFailureThreshold, o.serverDownPeriod)
+	if err != nil {
 		return nil, err
 	}
-	return &MemcachedCache{client: client}, nil
+	client := memcache.NewFromSelector(selector)
+	probe := memcache.NewFromSelector(probeSelector{selector})
+	probe.Timeout = client.Timeout
+
+	// Start as long as one server answers; the others count a failure.
+	if err := probe.Ping(); err != nil {
+		return nil, err
+	}
+
+	c := &MemcachedCache{client: client, probe: probe, selector: selector, opts: o}
+	if c.opts.sharedIndexKey != "" {
+		c.opts.keyIndex = &sharedKeyIndex{client: client, indexKey: c.opts.sharedIndexKey}
+	}
+	return c, nil
 }
 
+// MemcachedMaxTTL is the longest ttl memcached accepts; it reads larger
+// expirations as absolute Unix times.
+const MemcachedMaxTTL = 30 * 24 * time.Hour
+
 func (c *MemcachedCache) Set(key string, value interface{}, ttl time.Duration) error {
-	item := &memcache.Item{
-		Key:        key,
-		Value:      []byte(value.(string)),
-		Expiration: int32(ttl.Seconds()),
+	item := &memcache.Item{Key: key}
+	if err := c.fill(item, value, ttl); err != nil {
+		return err
 	}
-	return c.client.Set(item)
+	server := c.serverFor(key)
+	if err := c.track(server, c.client.Set(item)); err != nil {
+		return err
+	}
+	if c.opts.keyIndex != nil {
+		return c.opts.keyIndex.Add(key)
+	}
+	return nil
+}
+
+func (c *MemcachedCache) Add(key string, value interface{}, ttl time.Duration) error {
+	item := &memcache.Item{Key: key}
+	if err := c.fill(item, value, ttl); err != nil {
+		return err
+	}
+	server := c.serverFor(key)
+	err := c.track(server, c.client.Add(item))
+	if errors.Is(err, memcache.ErrNotStored) {
+		return ErrKeyExists
+	}
+	if err != nil {
+		return err
+	}
+	if c.opts.keyIndex != nil {
+		return c.opts.keyIndex.Add(key)
+	}
+	return nil
+}
+
+// Update writes the new value with compare-and-swap, which memcached
+// rejects if the key was written since it was read; it is then tried again
+// with the new value.
+func (c *MemcachedCache) Update(key string, fn UpdateFunc) error {
+	for attempt := 0; attempt < maxUpdateAttempts; attempt++ {
+		server := c.serverFor(key)
+		item, err := c.client.Get(key)
+		if err != nil {
+			return c.track(server, err)
+		}
+		current, err := c.opts.decode(item.Value, byte(item.Flags))
+		if err != nil {
+			return err
+		}
+		next, ttl, err := fn(current)
+		if err != nil {
+			return err
+		}
+		if err := c.fill(item, next, ttl); err != nil {
+			return err
+		}
+		err = c.track(server, c.client.CompareAndSwap(item))
+		switch {
+		case errors.Is(err, memcache.ErrCASConflict):
+			continue
+		case errors.Is(err, memcache.ErrNotStored):
+			// The key was deleted since it was read.
+			return ErrCacheMiss
+		}
+		return err
+	}
+	return ErrUpdateConflict
+}
+
+// fill encodes value into item and sets its expiration.
+func (c *MemcachedCache) fill(item *memcache.Item, value interface{}, ttl time.Duration) error {
+	if ttl > MemcachedMaxTTL {
+		return fmt.Errorf("memcached ttl %v exceeds the limit of %v", ttl, MemcachedMaxTTL)
+	}
+	data, marker, err := c.opts.encode(value)
+	if err != nil {
+		return err
+	}
+	item.Value = data
+	item.Flags = uint32(marker)
+	// Round up, since an expiration of zero seconds means no expiry.
+	item.Expiration = int32(math.Ceil(ttl.Seconds()))
+	return nil
 }
 
 func (c *MemcachedCache) Get(key string) (interface{}, error) {
+	server := c.serverFor(key)
 	item, err := c.client.Get(key)
 	if err != nil {
-		return nil, err
+		return nil, c.track(server, err)
 	}
-	return string(item.Value), nil
+	return c.opts.decode(item.Value, byte(item.Flags))
 }
 
 func (c *MemcachedCache) Delete(key string) error {
-	return c.client.Delete(key)
+	server := c.serverFor(key)
+	err := c.track(server, c.client.Delete(key))
+	if c.opts.keyIndex != nil && (err == nil || errors.Is(err, memcache.ErrCacheMiss)) {
+		if indexErr := c.opts.keyIndex.Remove(key); indexErr != nil {
+			return indexErr
+		}
+	}
+	return err
 }
 
 func (c *MemcachedCache) GetAll() (map[string]interface{}, error) {
-	// Memcached does not support GetAll in the same way as an in-memory cache.
-	return map[string]interface{}{}, nil
+	// Memcached cannot list its keys, so without a key index there is nothing to return.
+	if c.opts.keyIndex == nil {
+		return map[string]interface{}{}, nil
+	}
+	keys, err := c.indexedKeys("")
+	if err != nil {
+		return nil, err
+	}
+	return c.getMulti(keys)
+}
+
+// Scan lists the indexed keys in sorted order, see pageKeys.
+func (c *MemcachedCache) Scan(cursor string, match string, count int) (map[string]interface{}, string, error) {
+	if c.opts.keyIndex == nil {
+		return nil, "", errors.New("memcached cache has no key index")
+	}
+	keys, err := c.indexedKeys(match)
+	if err != nil {
+		return nil, "", err
+	}
+	keys, next := pageKeys(keys, cursor, count)
+	entries, err := c.getMulti(keys)
+	if err != nil {
+		return nil, "", err
+	}
+	return entries, next, nil
+}
+
+func (c *MemcachedCache) indexedKeys(match string) ([]string, error) {
+	keys, err := c.opts.keyIndex.Keys()
+	if err != nil {
+		return nil, err
+	}
+	filtered := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if key == c.opts.sharedIndexKey {
+			continue
+		}
+		if match != "" {
+			if ok, err := path.Match(match, key); err != nil {
+				return nil, fmt.Errorf("invalid match pattern %q: %w", match, err)
+			} else if !ok {
+				continue
+			}
+		}
+		filtered = append(filtered, key)
+	}
+	return filtered, nil
+}
+
+// getMulti fetches the given keys and drops the ones memcached no longer
+// has from the key index.
+func (c *MemcachedCache) getMulti(keys []string) (map[string]interface{}, error) {
+	entries := make(map[string]interface{}, len(keys))
+	if len(keys) == 0 {
+		return entries, nil
+	}
+	items, err := c.client.GetMulti(keys)
+	if err != nil {
+		return nil, err
+	}
+	var missing []string
+	for _, key := range keys {
+		item, found := items[key]
+		if !found {
+			missing = append(missing, key)
+			continue
+		}
+		value, err := c.opts.decode(item.Value, byte(item.Flags))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode %s: %w", key, err)
+		}
+		entries[key] = value
+	}
+	if len(missing) > 0 {
+		if err := c.opts.keyIndex.Remove(missing...); err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+// SetServers replaces the servers keys are spread over. Keys whose server
+// changed are missed until they are written again.
+func (c *MemcachedCache) SetServers(servers []MemcachedServer) error {
+	return c.selector.setServers(servers)
+}
+
+// Ping checks the servers that are up, see probeSelector.
+func (c *MemcachedCache) Ping() error {
+	return c.probe.Ping()
+}
+
+func (c *MemcachedCache) Stats() map[string]interface{} {
+	stats := c.opts.stats()
+	stats["servers"] = c.selector.status()
+	return stats
+}
+
+// serverFor returns the server a request for key is about to be sent to,
+// or "" when none is up. It is taken before the request, since a failure
+// of another request can move the key to the next server meanwhile.
+func (c *MemcachedCache) serverFor(key string) string {
+	server, _, _ := c.selector.pick(key)
+	return server
+}
+
+// track counts a failure of server, which a request was sent to, when err
+// shows it could not be reached; once it is marked down, following requests
+// are rehashed to the next server. Any other answer resets its failures.
+func (c *MemcachedCache) track(server string, err error) error {
+	if server == "" {
+		return err
+	}
+	if isServerFailure(err) {
+		c.selector.failed(server)
+	} else {
+		c.selector.answered(server)
+	}
+	return err
 }
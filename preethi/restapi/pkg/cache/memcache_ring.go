@@ -0,0 +1,252 @@
+//Server selection for MemcachedCache across several weighted servers
+
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bradfitz/gomemcache/memcache"
+)
+
+// defaultServerDownPeriod is how long a failed memcached server is skipped.
+const defaultServerDownPeriod = 30 * time.Second
+
+// defaultServerFailureThreshold is how many consecutive failures take a
+// memcached server out of the ring.
+const defaultServerFailureThreshold = 3
+
+type MemcachedServer struct {
+	Addr   string
+	Weight int
+}
+
+// ParseMemcachedServers parses addresses of the form "host:port" or
+// "host:port=weight".
+func ParseMemcachedServers(servers []string) ([]MemcachedServer, error) {
+	parsed := make([]MemcachedServer, 0, len(servers))
+	for _, server := range servers {
+		server = strings.TrimSpace(server)
+		if server == "" {
+			continue
+		}
+		addr, weight := server, 1
+		if i := strings.LastIndexByte(server, '='); i >= 0 {
+			var err error
+			addr = server[:i]
+			if weight, err = strconv.Atoi(server[i+1:]); err != nil || weight < 1 {
+				return nil, fmt.Errorf("invalid weight in memcached server %q", server)
+			}
+		}
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			return nil, fmt.Errorf("invalid memcached server %q: %w", server, err)
+		}
+		parsed = append(parsed, MemcachedServer{Addr: addr, Weight: weight})
+	}
+	if len(parsed) == 0 {
+		return nil, errors.New("no memcached servers configured")
+	}
+	return parsed, nil
+}
+
+// ringSelector is a memcache.ServerSelector that places keys on a
+// consistent hash ring and walks past servers that are marked down. A
+// server is marked down after failureThreshold consecutive failures, so a
+// single timeout does not move its keys. Its count is only reset when it
+// answers again, so a server still failing after its down period is marked
+// down again on the first failure.
+type ringSelector struct {
+	mutex            sync.RWMutex
+	ring             *HashRing
+	addrs            map[string]net.Addr
+	failures         map[string]int
+	failureThreshold int
+	downUntil        map[string]time.Time
+	downPeriod       time.Duration
+}
+
+func newRingSelector(servers []MemcachedServer, failureThreshold int, downPeriod time.Duration) (*ringSelector, error) {
+	s := &ringSelector{failureThreshold: failureThreshold, downPeriod: downPeriod}
+	if err := s.setServers(servers); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *ringSelector) setServers(servers []MemcachedServer) error {
+	weights := make(map[string]int, len(servers))
+	addrs := make(map[string]net.Addr, len(servers))
+	for _, server := range servers {
+		addr, err := net.ResolveTCPAddr("tcp", server.Addr)
+		if err != nil {
+			return err
+		}
+		weights[server.Addr] = server.Weight
+		addrs[server.Addr] = addr
+	}
+	ring := NewHashRing(weights)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.ring = ring
+	s.addrs = addrs
+	downUntil := make(map[string]time.Time)
+	for server, until := range s.downUntil {
+		if _, found := addrs[server]; found {
+			downUntil[server] = until
+		}
+	}
+	s.downUntil = downUntil
+	failures := make(map[string]int)
+	for server, count := range s.failures {
+		if _, found := addrs[server]; found {
+			failures[server] = count
+		}
+	}
+	s.failures = failures
+	return nil
+}
+
+func (s *ringSelector) PickServer(key string) (net.Addr, error) {
+	_, addr, err := s.pick(key)
+	return addr, err
+}
+
+func (s *ringSelector) pick(key string) (string, net.Addr, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	now := time.Now()
+	var picked string
+	s.ring.Walk(key, func(server string) bool {
+		if now.Before(s.downUntil[server]) {
+			return true
+		}
+		picked = server
+		return false
+	})
+	if picked == "" {
+		return "", nil, memcache.ErrNoServers
+	}
+	return picked, s.addrs[picked], nil
+}
+
+func (s *ringSelector) Each(f func(net.Addr) error) error {
+	s.mutex.RLock()
+	addrs := make([]net.Addr, 0, len(s.addrs))
+	for _, server := range s.ring.Nodes() {
+		addrs = append(addrs, s.addrs[server])
+	}
+	s.mutex.RUnlock()
+	for _, addr := range addrs {
+		if err := f(addr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// up returns the servers that are not marked down.
+func (s *ringSelector) up() map[string]net.Addr {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	now := time.Now()
+	up := make(map[string]net.Addr, len(s.addrs))
+	for server, addr := range s.addrs {
+		if !now.Before(s.downUntil[server]) {
+			up[server] = addr
+		}
+	}
+	return up
+}
+
+// failed counts a failure of server and marks it down at the threshold.
+func (s *ringSelector) failed(server string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if _, found := s.addrs[server]; !found {
+		return
+	}
+	s.failures[server]++
+	if s.failures[server] >= s.failureThreshold {
+		s.downUntil[server] = time.Now().Add(s.downPeriod)
+	}
+}
+
+// answered resets the consecutive failures of server.
+func (s *ringSelector) answered(server string) {
+	s.mutex.RLock()
+	failing := s.failures[server] > 0
+	s.mutex.RUnlock()
+	if failing {
+		s.mutex.Lock()
+		delete(s.failures, server)
+		s.mutex.Unlock()
+	}
+}
+
+func (s *ringSelector) status() map[string]string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	now := time.Now()
+	status := make(map[string]string, len(s.addrs))
+	for server := range s.addrs {
+		if now.Before(s.downUntil[server]) {
+			status[server] = "down"
+		} else {
+			status[server] = "up"
+		}
+	}
+	return status
+}
+
+// probeSelector is the selector of the client pinging a ringSelector's
+// servers. Keys of a server that is down move to the next one on the ring,
+// so a ping succeeds as long as one server answers; the failures of the
+// servers that do not are counted.
+type probeSelector struct {
+	*ringSelector
+}
+
+func (s probeSelector) Each(f func(net.Addr) error) error {
+	err := memcache.ErrNoServers
+	answered := false
+	for server, addr := range s.up() {
+		if pingErr := f(addr); pingErr != nil {
+			err = pingErr
+			s.failed(server)
+			continue
+		}
+		s.answered(server)
+		answered = true
+	}
+	if answered {
+		return nil
+	}
+	return err
+}
+
+// isServerFailure reports whether err means the server could not be
+// reached, as opposed to a normal protocol answer such as a cache miss.
+func isServerFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	for _, protocolErr := range []error{
+		memcache.ErrCacheMiss,
+		memcache.ErrCASConflict,
+		memcache.ErrNotStored,
+		memcache.ErrMalformedKey,
+		memcache.ErrNoServers,
+		memcache.ErrServerError,
+	} {
+		if errors.Is(err, protocolErr) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,134 @@
+//Options shared by the remote cache backends
+
+package cache
+
+import (
+	"fmt"
+	"time"
+)
+
+// Option configures optional behaviour of RedisCache and MemcachedCache.
+type Option func(*options)
+
+type options struct {
+	codec                  Codec
+	compression            Compression
+	compressionThreshold   int
+	compressionStats       *compressionStats
+	keyIndex               KeyIndex
+	sharedIndexKey         string
+	serverFailureThreshold int
+	serverDownPeriod       time.Duration
+}
+
+func newOptions(opts []Option) options {
+	o := options{
+		codec:                  JSONCodec{},
+		compressionStats:       &compressionStats{},
+		serverFailureThreshold: defaultServerFailureThreshold,
+		serverDownPeriod:       defaultServerDownPeriod,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithCodec sets the codec used for values that are not strings or byte slices.
+func WithCodec(codec Codec) Option {
+	return func(o *options) {
+		o.codec = codec
+	}
+}
+
+// WithCompression compresses encoded values of at least threshold bytes.
+func WithCompression(algorithm Compression, threshold int) Option {
+	return func(o *options) {
+		o.compression = algorithm
+		o.compressionThreshold = threshold
+	}
+}
+
+// WithKeyIndex lets MemcachedCache track its keys in the given index.
+func WithKeyIndex(index KeyIndex) Option {
+	return func(o *options) {
+		o.keyIndex = index
+	}
+}
+
+// WithSharedKeyIndex lets MemcachedCache track its keys in a list stored
+// under indexKey in memcached itself, shared by all instances.
+func WithSharedKeyIndex(indexKey string) Option {
+	return func(o *options) {
+		o.sharedIndexKey = indexKey
+	}
+}
+
+// WithServerFailureThreshold sets how many consecutive failures of a server
+// make MemcachedCache skip it.
+func WithServerFailureThreshold(failures int) Option {
+	return func(o *options) {
+		if failures > 0 {
+			o.serverFailureThreshold = failures
+		}
+	}
+}
+
+// WithServerDownPeriod sets how long MemcachedCache skips a server after it
+// failed to answer.
+func WithServerDownPeriod(period time.Duration) Option {
+	return func(o *options) {
+		o.serverDownPeriod = period
+	}
+}
+
+// encode converts a value to the stored payload and its marker.
+func (o *options) encode(value interface{}) ([]byte, byte, error) {
+	data, marker, err := EncodeValue(o.codec, value)
+	if err != nil {
+		return nil, 0, err
+	}
+	if o.compression == CompressionNone {
+		return data, marker, nil
+	}
+	if len(data) < o.compressionThreshold {
+		o.compressionStats.record(len(data), len(data), false)
+		return data, marker, nil
+	}
+	compressed, err := compress(o.compression, data)
+	if err != nil {
+		return nil, 0, err
+	}
+	// Payloads that do not shrink are stored as they are.
+	if len(compressed) >= len(data) {
+		o.compressionStats.record(len(data), len(data), false)
+		return data, marker, nil
+	}
+	o.compressionStats.record(len(data), len(compressed), true)
+	return compressed, marker | byte(o.compression)<<compressionShift, nil
+}
+
+// decode reverses encode using the marker stored with the payload.
+func (o *options) decode(data []byte, marker byte) (interface{}, error) {
+	if algorithm := Compression((marker & compressionMask) >> compressionShift); algorithm != CompressionNone {
+		var err error
+		data, err = decompress(algorithm, data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress value: %w", err)
+		}
+	}
+	return DecodeValue(data, marker)
+}
+
+func (o *options) stats() map[string]interface{} {
+	stats := map[string]interface{}{
+		"codec":       fmt.Sprintf("%T", o.codec),
+		"compression": o.compression.String(),
+	}
+	if o.compression != CompressionNone {
+		compression := o.compressionStats.snapshot()
+		compression["threshold"] = o.compressionThreshold
+		stats["compression_stats"] = compression
+	}
+	return stats
+}
@@ -4,42 +4,434 @@ package cache
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-redis/redis/v8"
 )
 
+type RedisTopology string
+
+const (
+	RedisSingle   RedisTopology = "single"
+	RedisSentinel RedisTopology = "sentinel"
+	RedisCluster  RedisTopology = "cluster"
+)
+
+// RedisConfig describes how to reach Redis. Addrs holds the node address for
+// a single node, the sentinel addresses for sentinel, or the seed nodes of a
+// cluster.
+type RedisConfig struct {
+	Topology   RedisTopology
+	Addrs      []string
+	MasterName string
+	Password   string
+	DB         int
+}
+
 type RedisCache struct {
-	client *redis.Client
+	client   redis.UniversalClient
+	topology RedisTopology
+	opts     options
 }
 
-func NewRedisCache(address string) (*RedisCache, error) {
-	client := redis.NewClient(&redis.Options{
-		Addr: address,
-	})
+func NewRedisCache(address string, opts ...Option) (*RedisCache, error) {
+	return NewRedisCacheWithConfig(RedisConfig{Topology: RedisSingle, Addrs: []string{address}}, opts...)
+}
+
+func NewRedisCacheWithConfig(config RedisConfig, opts ...Option) (*RedisCache, error) {
+	if len(config.Addrs) == 0 {
+		return nil, fmt.Errorf("no redis addresses configured")
+	}
+	var client redis.UniversalClient
+	switch config.Topology {
+	case RedisSingle, "":
+		client = redis.NewClient(&redis.Options{
+			Addr:     config.Addrs[0],
+			Password: config.Password,
+			DB:       config.DB,
+		})
+	case RedisSentinel:
+		if config.MasterName == "" {
+			return nil, fmt.Errorf("sentinel topology requires a master name")
+		}
+		client = redis.NewFailoverClient(&redis.FailoverOptions{
+			MasterName:    config.MasterName,
+			SentinelAddrs: config.Addrs,
+			Password:      config.Password,
+			DB:            config.DB,
+		})
+	case RedisCluster:
+		client = redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:    config.Addrs,
+			Password: config.Password,
+		})
+	default:
+		return nil, fmt.Errorf("unknown redis topology %q", config.Topology)
+	}
 	if err := client.Ping(context.Background()).Err(); err != nil {
+		client.Close()
 		return nil, err
 	}
-	return &RedisCache{client: client}, nil
+	topology := config.Topology
+	if topology == "" {
+		topology = RedisSingle
+	}
+	return &RedisCache{client: client, topology: topology, opts: newOptions(opts)}, nil
 }
 
 func (c *RedisCache) Set(key string, value interface{}, ttl time.Duration) error {
-	return c.client.Set(context.Background(), key, value, ttl).Err()
+	payload, err := c.encode(value)
+	if err != nil {
+		return err
+	}
+	return c.client.Set(context.Background(), key, payload, ttl).Err()
 }
 
 func (c *RedisCache) Get(key string) (interface{}, error) {
-	val, err := c.client.Get(context.Background(), key).Result()
+	val, err := c.client.Get(context.Background(), key).Bytes()
 	if err != nil {
 		return nil, err
 	}
-	return val, nil
+	return c.decode(val)
+}
+
+func (c *RedisCache) Add(key string, value interface{}, ttl time.Duration) error {
+	payload, err := c.encode(value)
+	if err != nil {
+		return err
+	}
+	added, err := c.client.SetNX(context.Background(), key, payload, ttl).Result()
+	if err != nil {
+		return err
+	}
+	if !added {
+		return ErrKeyExists
+	}
+	return nil
+}
+
+// Update watches key and writes the new value in a transaction, which
+// Redis aborts if another client wrote the key in between; it is then
+// tried again with the new value.
+func (c *RedisCache) Update(key string, fn UpdateFunc) error {
+	ctx := context.Background()
+	update := func(tx *redis.Tx) error {
+		val, err := tx.Get(ctx, key).Bytes()
+		if err != nil {
+			return err
+		}
+		current, err := c.decode(val)
+		if err != nil {
+			return err
+		}
+		next, ttl, err := fn(current)
+		if err != nil {
+			return err
+		}
+		payload, err := c.encode(next)
+		if err != nil {
+			return err
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			return pipe.Set(ctx, key, payload, ttl).Err()
+		})
+		return err
+	}
+	for attempt := 0; attempt < maxUpdateAttempts; attempt++ {
+		err := c.client.Watch(ctx, update, key)
+		if !errors.Is(err, redis.TxFailedErr) {
+			return err
+		}
+	}
+	return ErrUpdateConflict
 }
 
 func (c *RedisCache) Delete(key string) error {
 	return c.client.Del(context.Background(), key).Err()
 }
 
+// scanBatchSize is the SCAN count hint used when listing the whole keyspace.
+const scanBatchSize = 100
+
 func (c *RedisCache) GetAll() (map[string]interface{}, error) {
-	// Redis does not support GetAll in the same way as an in-memory cache.
-	return map[string]interface{}{}, nil
+	allItems := make(map[string]interface{})
+	cursor := ""
+	for {
+		entries, next, err := c.Scan(cursor, "", scanBatchSize)
+		if err != nil {
+			return nil, err
+		}
+		for key, value := range entries {
+			allItems[key] = value
+		}
+		if next == "" {
+			return allItems, nil
+		}
+		cursor = next
+	}
+}
+
+// Scan walks the keyspace with SCAN, so Redis is never blocked by KEYS, and
+// fetches the values of each page in a single pipeline. In a cluster the
+// masters are scanned one after another and the cursor has the form
+// "<shard>:<position>".
+func (c *RedisCache) Scan(cursor string, match string, count int) (map[string]interface{}, string, error) {
+	ctx := context.Background()
+	shards, err := c.shards(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	shard, position, err := parseRedisCursor(cursor, len(shards))
+	if err != nil {
+		return nil, "", err
+	}
+	keys, position, err := shards[shard].Scan(ctx, position, match, int64(count)).Result()
+	if err != nil {
+		return nil, "", err
+	}
+	entries, err := c.getMulti(ctx, keys)
+	if err != nil {
+		return nil, "", err
+	}
+	if position == 0 {
+		shard++
+		if shard == len(shards) {
+			return entries, "", nil
+		}
+	}
+	return entries, formatRedisCursor(shard, position, len(shards)), nil
+}
+
+func parseRedisCursor(cursor string, shards int) (int, uint64, error) {
+	if cursor == "" {
+		return 0, 0, nil
+	}
+	shard := 0
+	if i := strings.IndexByte(cursor, ':'); i >= 0 {
+		var err error
+		if shard, err = strconv.Atoi(cursor[:i]); err != nil || shard < 0 || shard >= shards {
+			return 0, 0, fmt.Errorf("invalid cursor %q", cursor)
+		}
+		cursor = cursor[i+1:]
+	}
+	position, err := strconv.ParseUint(cursor, 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid cursor %q", cursor)
+	}
+	return shard, position, nil
+}
+
+func formatRedisCursor(shard int, position uint64, shards int) string {
+	if shards == 1 {
+		return strconv.FormatUint(position, 10)
+	}
+	return strconv.Itoa(shard) + ":" + strconv.FormatUint(position, 10)
+}
+
+// shards returns the clients that together hold the keyspace: every master
+// of a cluster, ordered by address, or the client itself otherwise.
+func (c *RedisCache) shards(ctx context.Context) ([]redis.Cmdable, error) {
+	cluster, ok := c.client.(*redis.ClusterClient)
+	if !ok {
+		return []redis.Cmdable{c.client}, nil
+	}
+	var mutex sync.Mutex
+	var masters []*redis.Client
+	err := cluster.ForEachMaster(ctx, func(ctx context.Context, client *redis.Client) error {
+		mutex.Lock()
+		defer mutex.Unlock()
+		masters = append(masters, client)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(masters, func(i, j int) bool {
+		return masters[i].Options().Addr < masters[j].Options().Addr
+	})
+	shards := make([]redis.Cmdable, len(masters))
+	for i, master := range masters {
+		shards[i] = master
+	}
+	return shards, nil
+}
+
+func (c *RedisCache) GetMulti(keys []string) (map[string]interface{}, error) {
+	return c.getMulti(context.Background(), keys)
+}
+
+// SetMulti writes all entries in one pipeline; in a cluster the pipeline is
+// split by node.
+func (c *RedisCache) SetMulti(entries map[string]interface{}, ttl time.Duration) error {
+	ctx := context.Background()
+	pipe := c.client.Pipeline()
+	for key, value := range entries {
+		payload, err := c.encode(value)
+		if err != nil {
+			return fmt.Errorf("failed to encode %s: %w", key, err)
+		}
+		pipe.Set(ctx, key, payload, ttl)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// DeleteMatching removes every key matching the glob pattern from all
+// shards and returns how many keys were deleted.
+func (c *RedisCache) DeleteMatching(pattern string) (int64, error) {
+	ctx := context.Background()
+	shards, err := c.shards(ctx)
+	if err != nil {
+		return 0, err
+	}
+	var deleted int64
+	for _, shard := range shards {
+		var position uint64
+		for {
+			keys, next, err := shard.Scan(ctx, position, pattern, scanBatchSize).Result()
+			if err != nil {
+				return deleted, err
+			}
+			if len(keys) > 0 {
+				// Keys are unlinked one by one since a cluster rejects
+				// multi-key commands that span hash slots.
+				pipe := shard.Pipeline()
+				cmds := make([]*redis.IntCmd, len(keys))
+				for i, key := range keys {
+					cmds[i] = pipe.Unlink(ctx, key)
+				}
+				if _, err := pipe.Exec(ctx); err != nil {
+					return deleted, err
+				}
+				for _, cmd := range cmds {
+					deleted += cmd.Val()
+				}
+			}
+			if next == 0 {
+				break
+			}
+			position = next
+		}
+	}
+	return deleted, nil
+}
+
+func (c *RedisCache) Close() error {
+	return c.client.Close()
+}
+
+func (c *RedisCache) Topology() RedisTopology {
+	return c.topology
+}
+
+func (c *RedisCache) getMulti(ctx context.Context, keys []string) (map[string]interface{}, error) {
+	entries := make(map[string]interface{}, len(keys))
+	if len(keys) == 0 {
+		return entries, nil
+	}
+	pipe := c.client.Pipeline()
+	cmds := make([]*redis.StringCmd, len(keys))
+	for i, key := range keys {
+		cmds[i] = pipe.Get(ctx, key)
+	}
+	// Exec reports the first failed command; errors of single keys are
+	// handled below, so only failures of the connection are returned.
+	if _, err := pipe.Exec(ctx); err != nil && !isRedisError(err) {
+		return nil, err
+	}
+	for i, cmd := range cmds {
+		val, err := cmd.Bytes()
+		if err == redis.Nil {
+			// The key expired or was deleted after it was scanned.
+			continue
+		}
+		if isRedisError(err) {
+			// Keys holding lists, hashes and other types are not cache
+			// entries; one of them must not fail the whole page.
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		value, err := c.decode(val)
+		if err != nil {
+			// Neither is a value written by another client in a format
+			// this cache cannot read.
+			continue
+		}
+		entries[keys[i]] = value
+	}
+	return entries, nil
+}
+
+// isRedisError reports whether err is a reply of the server, such as
+// WRONGTYPE, rather than a failure to reach it.
+func isRedisError(err error) bool {
+	var replyErr redis.Error
+	return errors.As(err, &replyErr)
+}
+
+// Expirations reads the PTTL of every key in one pipeline.
+func (c *RedisCache) Expirations(keys []string) (map[string]time.Time, error) {
+	ctx := context.Background()
+	expirations := make(map[string]time.Time, len(keys))
+	if len(keys) == 0 {
+		return expirations, nil
+	}
+	pipe := c.client.Pipeline()
+	cmds := make([]*redis.DurationCmd, len(keys))
+	for i, key := range keys {
+		cmds[i] = pipe.PTTL(ctx, key)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for i, cmd := range cmds {
+		// PTTL answers -2 for missing keys and -1 for keys without expiry.
+		switch ttl := cmd.Val(); {
+		case ttl == -1:
+			expirations[keys[i]] = time.Time{}
+		case ttl >= 0:
+			expirations[keys[i]] = now.Add(ttl)
+		}
+	}
+	return expirations, nil
+}
+
+func (c *RedisCache) Ping() error {
+	return c.client.Ping(context.Background()).Err()
+}
+
+func (c *RedisCache) Stats() map[string]interface{} {
+	return c.opts.stats()
+}
+
+// redisHeader is set in the header byte of every value written with a codec.
+// Bytes 0x80-0x9f only continue a UTF-8 sequence, so text stored before
+// codecs were introduced never starts with one and is read back as is.
+const redisHeader byte = 0x80
+
+// encode stores the value marker as a header byte in front of the payload.
+func (c *RedisCache) encode(value interface{}) ([]byte, error) {
+	data, marker, err := c.opts.encode(value)
+	if err != nil {
+		return nil, err
+	}
+	payload := make([]byte, 0, len(data)+1)
+	payload = append(payload, redisHeader|marker)
+	return append(payload, data...), nil
+}
+
+func (c *RedisCache) decode(val []byte) (interface{}, error) {
+	if len(val) == 0 || val[0]&^(codecMask|compressionMask) != redisHeader {
+		return string(val), nil
+	}
+	return c.opts.decode(val[1:], val[0]&^redisHeader)
 }
@@ -0,0 +1,317 @@
+//Circuit breaker and retries in front of a remote cache
+
+package cache
+
+import (
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/bradfitz/gomemcache/memcache"
+)
+
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+type BreakerState string
+
+const (
+	BreakerClosed   BreakerState = "closed"
+	BreakerOpen     BreakerState = "open"
+	BreakerHalfOpen BreakerState = "half-open"
+)
+
+type ResiliencePolicy struct {
+	// The breaker opens when at least MinRequests calls were made in the
+	// last Window and FailureRate of them failed. Calls slower than
+	// SlowCall count as failures even when they succeed.
+	FailureRate float64
+	MinRequests int
+	Window      time.Duration
+	SlowCall    time.Duration
+	// An open breaker rejects calls for OpenTimeout, then lets HalfOpenProbes
+	// calls through; it closes when they all succeed.
+	OpenTimeout    time.Duration
+	HalfOpenProbes int
+	// Failed calls are retried up to Retries times, waiting a jittered
+	// exponential backoff between BaseBackoff and MaxBackoff.
+	Retries     int
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+}
+
+func DefaultResiliencePolicy() ResiliencePolicy {
+	return ResiliencePolicy{
+		FailureRate:    0.5,
+		MinRequests:    10,
+		Window:         10 * time.Second,
+		SlowCall:       time.Second,
+		OpenTimeout:    10 * time.Second,
+		HalfOpenProbes: 3,
+		Retries:        2,
+		BaseBackoff:    20 * time.Millisecond,
+		MaxBackoff:     500 * time.Millisecond,
+	}
+}
+
+type circuitBreaker struct {
+	policy ResiliencePolicy
+
+	mutex       sync.Mutex
+	state       BreakerState
+	windowStart time.Time
+	calls       int
+	failures    int
+	openedAt    time.Time
+	probes      int
+	probeOKs    int
+	trips       int
+}
+
+// allow reports whether a call may go ahead, or how long the breaker stays open.
+func (b *circuitBreaker) allow() (bool, time.Duration) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	switch b.state {
+	case BreakerOpen:
+		remaining := b.policy.OpenTimeout - time.Since(b.openedAt)
+		if remaining > 0 {
+			return false, remaining
+		}
+		b.state = BreakerHalfOpen
+		b.probes, b.probeOKs = 0, 0
+		fallthrough
+	case BreakerHalfOpen:
+		if b.probes >= b.policy.HalfOpenProbes {
+			return false, b.policy.OpenTimeout
+		}
+		b.probes++
+	}
+	return true, 0
+}
+
+func (b *circuitBreaker) record(failed bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	now := time.Now()
+	switch b.state {
+	case BreakerHalfOpen:
+		if failed {
+			b.open(now)
+			return
+		}
+		b.probeOKs++
+		if b.probeOKs >= b.policy.HalfOpenProbes {
+			b.state = BreakerClosed
+			b.windowStart, b.calls, b.failures = now, 0, 0
+		}
+	case BreakerClosed:
+		if now.Sub(b.windowStart) > b.policy.Window {
+			b.windowStart, b.calls, b.failures = now, 0, 0
+		}
+		b.calls++
+		if failed {
+			b.failures++
+		}
+		if b.calls >= b.policy.MinRequests && float64(b.failures) >= b.policy.FailureRate*float64(b.calls) {
+			b.open(now)
+		}
+	}
+}
+
+func (b *circuitBreaker) open(now time.Time) {
+	b.state = BreakerOpen
+	b.openedAt = now
+	b.trips++
+}
+
+func (b *circuitBreaker) stats() map[string]interface{} {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return map[string]interface{}{
+		"state":    b.state,
+		"calls":    b.calls,
+		"failures": b.failures,
+		"trips":    b.trips,
+	}
+}
+
+// ResilientCache wraps a remote cache with a circuit breaker, so a slow or
+// failing backend is answered with an UnavailableError right away instead
+// of making every request wait, and retries failed calls. Every Cache
+// operation overwrites or reads, so all of them are safe to retry; Add is
+// the exception.
+type ResilientCache struct {
+	cache   Cache
+	name    string
+	policy  ResiliencePolicy
+	breaker *circuitBreaker
+
+	mutex   sync.Mutex
+	retries int
+}
+
+func NewResilientCache(name string, cache Cache, policy ResiliencePolicy) *ResilientCache {
+	return &ResilientCache{
+		cache:   cache,
+		name:    name,
+		policy:  policy,
+		breaker: &circuitBreaker{policy: policy, state: BreakerClosed, windowStart: time.Now()},
+	}
+}
+
+func (c *ResilientCache) Set(key string, value interface{}, ttl time.Duration) error {
+	return c.do(func() error {
+		return c.cache.Set(key, value, ttl)
+	})
+}
+
+// Add is tried once: if an add succeeded but its answer was lost, a retry
+// would report the key as already present.
+func (c *ResilientCache) Add(key string, value interface{}, ttl time.Duration) error {
+	_, err := c.attempt(func() error {
+		return Add(c.cache, key, value, ttl)
+	}, nil)
+	return err
+}
+
+// Update is tried once as well: if its write succeeded but the answer was
+// lost, a retry would apply fn a second time.
+func (c *ResilientCache) Update(key string, fn UpdateFunc) error {
+	_, err := c.attempt(func() error {
+		return Update(c.cache, key, fn)
+	}, nil)
+	return err
+}
+
+func (c *ResilientCache) Get(key string) (interface{}, error) {
+	var value interface{}
+	err := c.do(func() error {
+		var err error
+		value, err = c.cache.Get(key)
+		return err
+	})
+	return value, err
+}
+
+func (c *ResilientCache) Delete(key string) error {
+	return c.do(func() error {
+		return c.cache.Delete(key)
+	})
+}
+
+func (c *ResilientCache) GetAll() (map[string]interface{}, error) {
+	var entries map[string]interface{}
+	err := c.do(func() error {
+		var err error
+		entries, err = c.cache.GetAll()
+		return err
+	})
+	return entries, err
+}
+
+func (c *ResilientCache) Scan(cursor string, match string, count int) (map[string]interface{}, string, error) {
+	var entries map[string]interface{}
+	var next string
+	err := c.do(func() error {
+		var err error
+		entries, next, err = Scan(c.cache, cursor, match, count)
+		return err
+	})
+	return entries, next, err
+}
+
+func (c *ResilientCache) Expirations(keys []string) (map[string]time.Time, error) {
+	var expirations map[string]time.Time
+	err := c.do(func() error {
+		var err error
+		expirations, err = Expirations(c.cache, keys)
+		return err
+	})
+	return expirations, err
+}
+
+// BreakerState is exposed for health checks.
+func (c *ResilientCache) BreakerState() BreakerState {
+	c.breaker.mutex.Lock()
+	defer c.breaker.mutex.Unlock()
+	return c.breaker.state
+}
+
+func (c *ResilientCache) Ping() error {
+	return Ping(c.cache)
+}
+
+func (c *ResilientCache) Stats() map[string]interface{} {
+	c.mutex.Lock()
+	retries := c.retries
+	c.mutex.Unlock()
+	stats := map[string]interface{}{
+		"circuit_breaker": c.breaker.stats(),
+		"retries":         retries,
+	}
+	if provider, ok := c.cache.(StatsProvider); ok {
+		for k, v := range provider.Stats() {
+			stats[k] = v
+		}
+	}
+	return stats
+}
+
+func (c *ResilientCache) do(call func() error) error {
+	var err error
+	for attempt := 0; attempt <= c.policy.Retries; attempt++ {
+		if attempt > 0 {
+			c.mutex.Lock()
+			c.retries++
+			c.mutex.Unlock()
+			time.Sleep(c.backoff(attempt))
+		}
+		var failed bool
+		if failed, err = c.attempt(call, err); !failed {
+			return err
+		}
+	}
+	return err
+}
+
+// attempt makes one call through the breaker and reports whether it failed
+// in a way worth retrying. previous is the error of the last attempt.
+func (c *ResilientCache) attempt(call func() error, previous error) (bool, error) {
+	allowed, retryAfter := c.breaker.allow()
+	if !allowed {
+		if previous == nil {
+			previous = ErrCircuitOpen
+		}
+		return false, &UnavailableError{Backend: c.name, RetryAfter: retryAfter, Err: previous}
+	}
+	start := time.Now()
+	err := call()
+	failed := isBackendFailure(err)
+	c.breaker.record(failed || time.Since(start) > c.policy.SlowCall)
+	return failed, err
+}
+
+// backoff doubles with every attempt and picks a random wait in the upper
+// half, so clients retrying together spread out.
+func (c *ResilientCache) backoff(attempt int) time.Duration {
+	backoff := c.policy.BaseBackoff << (attempt - 1)
+	if backoff <= 0 || backoff > c.policy.MaxBackoff {
+		backoff = c.policy.MaxBackoff
+	}
+	return backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+}
+
+// isBackendFailure reports whether err means the backend could not be
+// reached or timed out, as opposed to an answer such as a cache miss or a
+// value that cannot be encoded, which retrying would not change.
+func isBackendFailure(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, memcache.ErrNoServers) ||
+		errors.Is(err, memcache.ErrServerError)
+}
@@ -0,0 +1,164 @@
+//Two level cache with a local L1 in front of a shared L2
+
+package cache
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// TieredCache reads from L1 first and falls back to L2, copying L2 hits
+// into L1. Writes and deletes go to both levels, L2 first since it is the
+// shared source of truth. Entries live in L1 for at most l1TTL so other
+// instances' writes to L2 become visible again.
+type TieredCache struct {
+	l1     Cache
+	l2     Cache
+	l1TTL  time.Duration
+	l1Hits int64
+	l2Hits int64
+	misses int64
+}
+
+func NewTieredCache(l1, l2 Cache, l1TTL time.Duration) *TieredCache {
+	return &TieredCache{l1: l1, l2: l2, l1TTL: l1TTL}
+}
+
+func (c *TieredCache) Set(key string, value interface{}, ttl time.Duration) error {
+	if err := c.l2.Set(key, value, ttl); err != nil {
+		return err
+	}
+	return c.l1.Set(key, value, c.promotionTTL(ttl))
+}
+
+// Add and Update are decided by L2; L1 takes the result afterwards.
+func (c *TieredCache) Add(key string, value interface{}, ttl time.Duration) error {
+	if err := Add(c.l2, key, value, ttl); err != nil {
+		return err
+	}
+	return c.l1.Set(key, value, c.promotionTTL(ttl))
+}
+
+func (c *TieredCache) Update(key string, fn UpdateFunc) error {
+	var next interface{}
+	var nextTTL time.Duration
+	err := Update(c.l2, key, func(current interface{}) (interface{}, time.Duration, error) {
+		var err error
+		next, nextTTL, err = fn(current)
+		return next, nextTTL, err
+	})
+	if err != nil {
+		return err
+	}
+	return c.l1.Set(key, next, c.promotionTTL(nextTTL))
+}
+
+func (c *TieredCache) Get(key string) (interface{}, error) {
+	if value, err := c.l1.Get(key); err == nil {
+		atomic.AddInt64(&c.l1Hits, 1)
+		return value, nil
+	}
+	value, err := c.l2.Get(key)
+	if err != nil {
+		if IsCacheMiss(err) {
+			atomic.AddInt64(&c.misses, 1)
+		}
+		return nil, err
+	}
+	atomic.AddInt64(&c.l2Hits, 1)
+	// A failed promotion only costs another L2 read next time.
+	if ttl, ok := c.remainingTTL(key, value); ok {
+		c.l1.Set(key, value, c.promotionTTL(ttl))
+	}
+	return value, nil
+}
+
+// remainingTTL is how much longer value, read from L2, lives there, with
+// 0 when it does not expire or L2 cannot tell. It is not ok when the value
+// has expired meanwhile.
+func (c *TieredCache) remainingTTL(key string, value interface{}) (time.Duration, bool) {
+	expiresAt := time.Time{}
+	if typed, isTyped := value.(TypedValue); isTyped {
+		expiresAt = typed.ExpiresAt
+	} else if expirations, err := Expirations(c.l2, []string{key}); err == nil {
+		expiresAt = expirations[key]
+	}
+	if expiresAt.IsZero() {
+		return 0, true
+	}
+	ttl := time.Until(expiresAt)
+	return ttl, ttl > 0
+}
+
+func (c *TieredCache) Expirations(keys []string) (map[string]time.Time, error) {
+	return Expirations(c.l2, keys)
+}
+
+func (c *TieredCache) Delete(key string) error {
+	l2Err := c.l2.Delete(key)
+	if l2Err != nil && !IsCacheMiss(l2Err) {
+		return l2Err
+	}
+	l1Err := c.l1.Delete(key)
+	if l1Err != nil && !IsCacheMiss(l1Err) {
+		return l1Err
+	}
+	if l1Err != nil && l2Err != nil {
+		return ErrCacheMiss
+	}
+	return nil
+}
+
+func (c *TieredCache) GetAll() (map[string]interface{}, error) {
+	l1Items, err := c.l1.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	l2Items, err := c.l2.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	allItems := make(map[string]interface{}, len(l1Items)+len(l2Items))
+	for key, value := range l1Items {
+		allItems[key] = value
+	}
+	for key, value := range l2Items {
+		allItems[key] = value
+	}
+	return allItems, nil
+}
+
+// Scan lists L2, which holds every entry of L1 as well.
+func (c *TieredCache) Scan(cursor string, match string, count int) (map[string]interface{}, string, error) {
+	return Scan(c.l2, cursor, match, count)
+}
+
+// Ping checks the shared level; the local level is always reachable.
+func (c *TieredCache) Ping() error {
+	return Ping(c.l2)
+}
+
+func (c *TieredCache) Stats() map[string]interface{} {
+	stats := map[string]interface{}{
+		"l1_hits": atomic.LoadInt64(&c.l1Hits),
+		"l2_hits": atomic.LoadInt64(&c.l2Hits),
+		"misses":  atomic.LoadInt64(&c.misses),
+		"l1_ttl":  c.l1TTL.String(),
+	}
+	if provider, ok := c.l1.(StatsProvider); ok {
+		stats["l1"] = provider.Stats()
+	}
+	if provider, ok := c.l2.(StatsProvider); ok {
+		stats["l2"] = provider.Stats()
+	}
+	return stats
+}
+
+// promotionTTL keeps L1 entries no longer than l1TTL and never longer than
+// the entry itself lives in L2.
+func (c *TieredCache) promotionTTL(ttl time.Duration) time.Duration {
+	if ttl > 0 && ttl < c.l1TTL {
+		return ttl
+	}
+	return c.l1TTL
+}
@@ -0,0 +1,81 @@
+//Placeholder for a backend that could not be reached
+
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrBackendUnavailable = errors.New("backend unavailable")
+
+// UnavailableError is returned by a backend that cannot serve requests
+// right now. RetryAfter is when it is worth trying again.
+type UnavailableError struct {
+	Backend    string
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *UnavailableError) Error() string {
+	return fmt.Sprintf("%s is unavailable: %v", e.Backend, e.Err)
+}
+
+func (e *UnavailableError) Unwrap() error {
+	return e.Err
+}
+
+func (e *UnavailableError) Is(target error) bool {
+	return target == ErrBackendUnavailable
+}
+
+// UnavailableCache stands in for a backend that failed to connect, and
+// answers every call with an UnavailableError until it is replaced.
+type UnavailableCache struct {
+	err *UnavailableError
+}
+
+func NewUnavailableCache(backend string, err error, retryAfter time.Duration) *UnavailableCache {
+	return &UnavailableCache{err: &UnavailableError{Backend: backend, RetryAfter: retryAfter, Err: err}}
+}
+
+func (c *UnavailableCache) Set(key string, value interface{}, ttl time.Duration) error {
+	return c.err
+}
+
+func (c *UnavailableCache) Get(key string) (interface{}, error) {
+	return nil, c.err
+}
+
+func (c *UnavailableCache) Add(key string, value interface{}, ttl time.Duration) error {
+	return c.err
+}
+
+func (c *UnavailableCache) Update(key string, fn UpdateFunc) error {
+	return c.err
+}
+
+func (c *UnavailableCache) Delete(key string) error {
+	return c.err
+}
+
+func (c *UnavailableCache) GetAll() (map[string]interface{}, error) {
+	return nil, c.err
+}
+
+func (c *UnavailableCache) Scan(cursor string, match string, count int) (map[string]interface{}, string, error) {
+	return nil, "", c.err
+}
+
+func (c *UnavailableCache) Ping() error {
+	return c.err
+}
+
+func (c *UnavailableCache) Stats() map[string]interface{} {
+	return map[string]interface{}{
+		"status":      "degraded",
+		"error":       c.err.Err.Error(),
+		"retry_after": c.err.RetryAfter.String(),
+	}
+}
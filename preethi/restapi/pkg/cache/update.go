@@ -0,0 +1,64 @@
+//Conditional writes: adding absent keys and read-modify-write updates
+
+package cache
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrKeyExists = errors.New("key already exists")
+	// ErrUpdateConflict means concurrent writers kept changing the key.
+	ErrUpdateConflict = errors.New("key changed concurrently")
+)
+
+// maxUpdateAttempts bounds the optimistic retries of an update whose key
+// was changed by another writer in the meantime.
+const maxUpdateAttempts = 10
+
+// UpdateFunc computes the value replacing current, and its ttl.
+type UpdateFunc func(current interface{}) (interface{}, time.Duration, error)
+
+// Updater is implemented by caches that can write conditionally without
+// racing other writers.
+type Updater interface {
+	// Add stores value only if key is absent, or returns ErrKeyExists.
+	Add(key string, value interface{}, ttl time.Duration) error
+	// Update replaces the value of key with the result of fn, or returns a
+	// cache miss if key is absent.
+	Update(key string, fn UpdateFunc) error
+}
+
+// Add stores value unless key is present. Caches that are not Updaters
+// check and set in two steps, which other writers can interleave.
+func Add(c Cache, key string, value interface{}, ttl time.Duration) error {
+	if updater, ok := c.(Updater); ok {
+		return updater.Add(key, value, ttl)
+	}
+	_, err := c.Get(key)
+	if err == nil {
+		return ErrKeyExists
+	}
+	if !IsCacheMiss(err) {
+		return err
+	}
+	return c.Set(key, value, ttl)
+}
+
+// Update replaces the value of key with the result of fn, atomically for
+// Updaters and with a plain read and write otherwise.
+func Update(c Cache, key string, fn UpdateFunc) error {
+	if updater, ok := c.(Updater); ok {
+		return updater.Update(key, fn)
+	}
+	current, err := c.Get(key)
+	if err != nil {
+		return err
+	}
+	next, ttl, err := fn(current)
+	if err != nil {
+		return err
+	}
+	return c.Set(key, next, ttl)
+}
@@ -0,0 +1,499 @@
+//Sharded in-memory cache spread over a group of peer instances
+
+package cluster
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Preethi0716/Cache-Library/preethi/restapi/pkg/cache"
+	"github.com/gorilla/mux"
+)
+
+// peerTimeout bounds every request to another peer.
+const peerTimeout = 2 * time.Second
+
+// updateAttempts bounds how often an update of a key owned by another peer
+// is tried again after the key changed between reading and writing it.
+const updateAttempts = 10
+
+// errPreconditionFailed means the owner did not store a conditional write
+// because the key was not in the expected state.
+var errPreconditionFailed = errors.New("peer entry changed")
+
+// conditionalWrite is the body of a POST to /_peer/cache/{key}. Entry is
+// stored if the key is absent when Expected is nil, or else if the key
+// still holds the value of Expected.
+type conditionalWrite struct {
+	Entry    wireEntry  `json:"entry"`
+	Expected *wireEntry `json:"expected,omitempty"`
+}
+
+// peerPage is a page of the entries a peer owns, answered by /_peer/scan.
+type peerPage struct {
+	Entries []wireEntry `json:"entries"`
+	Next    string      `json:"next"`
+}
+
+// PeerCache pools the memory of several instances. Every key is owned by
+// one peer chosen on a consistent hash ring; other peers forward reads and
+// writes to the owner over HTTP and keep recently read remote values in a
+// small hot cache for hotTTL.
+type PeerCache struct {
+	self   string
+	local  *cache.LRUCache
+	hot    *cache.LRUCache
+	hotTTL time.Duration
+	client *http.Client
+
+	mutex sync.RWMutex
+	ring  *cache.HashRing
+}
+
+// NewPeerCache creates the cache of the peer reachable at self, a base URL
+// such as http://localhost:8081. self is added to peers when missing.
+func NewPeerCache(self string, peers []string, capacity, hotCapacity int, hotTTL time.Duration) *PeerCache {
+	p := &PeerCache{
+		self:   strings.TrimRight(self, "/"),
+		local:  cache.NewLRUCache(capacity),
+		hot:    cache.NewLRUCache(hotCapacity),
+		hotTTL: hotTTL,
+		client: &http.Client{Timeout: peerTimeout},
+	}
+	p.ring = newPeerRing(p.self, peers)
+	return p
+}
+
+// SetTransport changes how requests to the other peers are sent, such as
+// to authenticate them.
+func (p *PeerCache) SetTransport(transport http.RoundTripper) {
+	p.client.Transport = transport
+}
+
+func newPeerRing(self string, peers []string) *cache.HashRing {
+	weights := map[string]int{self: 1}
+	for _, peer := range peers {
+		if peer = strings.TrimRight(strings.TrimSpace(peer), "/"); peer != "" {
+			weights[peer] = 1
+		}
+	}
+	return cache.NewHashRing(weights)
+}
+
+func (p *PeerCache) owner(key string) string {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	owner, _ := p.ring.Get(key)
+	return owner
+}
+
+func (p *PeerCache) Set(key string, value interface{}, ttl time.Duration) error {
+	owner := p.owner(key)
+	if owner == p.self {
+		return p.local.Set(key, value, ttl)
+	}
+	p.hot.Delete(key)
+	entry, err := newWireEntry(key, value, cache.ExpiresAt(ttl))
+	if err != nil {
+		return err
+	}
+	return p.put(owner, entry)
+}
+
+// Add and Update are decided by the owner, so they hold across peers.
+func (p *PeerCache) Add(key string, value interface{}, ttl time.Duration) error {
+	owner := p.owner(key)
+	if owner == p.self {
+		return p.local.Add(key, value, ttl)
+	}
+	p.hot.Delete(key)
+	entry, err := newWireEntry(key, value, cache.ExpiresAt(ttl))
+	if err != nil {
+		return err
+	}
+	err = p.do(http.MethodPost, owner+"/_peer/cache/"+url.PathEscape(key), conditionalWrite{Entry: entry}, nil)
+	if errors.Is(err, errPreconditionFailed) {
+		return cache.ErrKeyExists
+	}
+	return err
+}
+
+// Update reads the value from its owner, applies fn here and writes the
+// result back only if the owner still holds the value read, trying again
+// otherwise.
+func (p *PeerCache) Update(key string, fn cache.UpdateFunc) error {
+	owner := p.owner(key)
+	if owner == p.self {
+		return p.local.Update(key, fn)
+	}
+	p.hot.Delete(key)
+	target := owner + "/_peer/cache/" + url.PathEscape(key)
+	for attempt := 0; attempt < updateAttempts; attempt++ {
+		var current wireEntry
+		if err := p.do(http.MethodGet, target, nil, &current); err != nil {
+			return err
+		}
+		value, err := current.value()
+		if err != nil {
+			return err
+		}
+		next, ttl, err := fn(value)
+		if err != nil {
+			return err
+		}
+		entry, err := newWireEntry(key, next, cache.ExpiresAt(ttl))
+		if err != nil {
+			return err
+		}
+		err = p.do(http.MethodPost, target, conditionalWrite{Entry: entry, Expected: &current}, nil)
+		if !errors.Is(err, errPreconditionFailed) {
+			return err
+		}
+	}
+	return cache.ErrUpdateConflict
+}
+
+func (p *PeerCache) Get(key string) (interface{}, error) {
+	owner := p.owner(key)
+	if owner == p.self {
+		return p.local.Get(key)
+	}
+	if value, err := p.hot.Get(key); err == nil {
+		return value, nil
+	}
+	var entry wireEntry
+	if err := p.do(http.MethodGet, owner+"/_peer/cache/"+url.PathEscape(key), nil, &entry); err != nil {
+		return nil, err
+	}
+	value, err := entry.value()
+	if err != nil {
+		return nil, err
+	}
+	hotTTL := p.hotTTL
+	if ttl := entry.ttl(); ttl > 0 && ttl < hotTTL {
+		hotTTL = ttl
+	}
+	p.hot.Set(key, value, hotTTL)
+	return value, nil
+}
+
+func (p *PeerCache) Delete(key string) error {
+	owner := p.owner(key)
+	if owner == p.self {
+		return p.local.Delete(key)
+	}
+	p.hot.Delete(key)
+	return p.do(http.MethodDelete, owner+"/_peer/cache/"+url.PathEscape(key), nil, nil)
+}
+
+// GetAll collects the entries owned by every peer.
+func (p *PeerCache) GetAll() (map[string]interface{}, error) {
+	allItems := make(map[string]interface{})
+	for _, peer := range p.Peers() {
+		var entries []wireEntry
+		if peer == p.self {
+			entries = p.localEntries()
+		} else if err := p.do(http.MethodGet, peer+"/_peer/entries", nil, &entries); err != nil {
+			return nil, fmt.Errorf("peer %s: %w", peer, err)
+		}
+		for _, entry := range entries {
+			value, err := entry.value()
+			if err != nil {
+				return nil, err
+			}
+			allItems[entry.Key] = value
+		}
+	}
+	return allItems, nil
+}
+
+// Scan pages the peers one after the other, in the order of their URLs.
+// The cursor is the URL of the peer being listed and its own cursor, joined
+// by a space; a peer that left is skipped.
+func (p *PeerCache) Scan(cursor string, match string, count int) (map[string]interface{}, string, error) {
+	peers := p.Peers()
+	sort.Strings(peers)
+	peer, peerCursor, _ := strings.Cut(cursor, " ")
+	start := 0
+	if cursor != "" {
+		start = sort.SearchStrings(peers, peer)
+		if start < len(peers) && peers[start] != peer {
+			peerCursor = ""
+		}
+	}
+	if start >= len(peers) {
+		return map[string]interface{}{}, "", nil
+	}
+	peer = peers[start]
+
+	var page peerPage
+	if peer == p.self {
+		var err error
+		if page, err = p.localPage(peerCursor, match, count); err != nil {
+			return nil, "", err
+		}
+	} else {
+		query := url.Values{"cursor": {peerCursor}, "match": {match}, "count": {strconv.Itoa(count)}}
+		if err := p.do(http.MethodGet, peer+"/_peer/scan?"+query.Encode(), nil, &page); err != nil {
+			return nil, "", fmt.Errorf("peer %s: %w", peer, err)
+		}
+	}
+	entries := make(map[string]interface{}, len(page.Entries))
+	for _, entry := range page.Entries {
+		value, err := entry.value()
+		if err != nil {
+			return nil, "", err
+		}
+		entries[entry.Key] = value
+	}
+	switch {
+	case page.Next != "":
+		return entries, peer + " " + page.Next, nil
+	case start+1 < len(peers):
+		return entries, peers[start+1] + " ", nil
+	}
+	return entries, "", nil
+}
+
+func (p *PeerCache) Peers() []string {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	return p.ring.Nodes()
+}
+
+// SetPeers changes the group membership and hands the local entries that
+// now belong to another peer over to it. Entries that cannot be handed over
+// stay here and are only reachable again once ownership moves back.
+func (p *PeerCache) SetPeers(peers []string) error {
+	ring := newPeerRing(p.self, peers)
+	p.mutex.Lock()
+	p.ring = ring
+	p.mutex.Unlock()
+	p.hot.Flush()
+
+	var failed []string
+	for _, item := range p.local.Items() {
+		owner, _ := ring.Get(item.Key())
+		if owner == p.self {
+			continue
+		}
+		entry, err := newWireEntry(item.Key(), item.Value(), item.Expiration())
+		if err == nil {
+			err = p.put(owner, entry)
+		}
+		if err != nil {
+			failed = append(failed, item.Key())
+			continue
+		}
+		p.local.Delete(item.Key())
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to hand over %d keys: %s", len(failed), strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+func (p *PeerCache) Stats() map[string]interface{} {
+	return map[string]interface{}{
+		"self":  p.self,
+		"peers": p.Peers(),
+		"local": p.local.Stats(),
+		"hot":   p.hot.Stats(),
+	}
+}
+
+func (p *PeerCache) localEntries() []wireEntry {
+	items := p.local.Items()
+	entries := make([]wireEntry, 0, len(items))
+	for _, item := range items {
+		if entry, err := newWireEntry(item.Key(), item.Value(), item.Expiration()); err == nil {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+func (p *PeerCache) localPage(cursor string, match string, count int) (peerPage, error) {
+	items, next, err := p.local.ScanItems(cursor, match, count)
+	if err != nil {
+		return peerPage{}, err
+	}
+	page := peerPage{Entries: make([]wireEntry, 0, len(items)), Next: next}
+	for _, item := range items {
+		if entry, err := newWireEntry(item.Key(), item.Value(), item.Expiration()); err == nil {
+			page.Entries = append(page.Entries, entry)
+		}
+	}
+	return page, nil
+}
+
+func (p *PeerCache) put(owner string, entry wireEntry) error {
+	return p.do(http.MethodPut, owner+"/_peer/cache/"+url.PathEscape(entry.Key), entry, nil)
+}
+
+func (p *PeerCache) do(method, target string, body interface{}, out interface{}) error {
+	var payload bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&payload).Encode(body); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequest(method, target, &payload)
+	if err != nil {
+		return err
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return cache.ErrCacheMiss
+	case resp.StatusCode == http.StatusPreconditionFailed:
+		return errPreconditionFailed
+	case resp.StatusCode >= 300:
+		return fmt.Errorf("peer %s answered %s", target, resp.Status)
+	}
+	if out != nil {
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+	return nil
+}
+
+// RegisterRoutes adds the endpoints other peers call to r.
+func (p *PeerCache) RegisterRoutes(r *mux.Router) {
+	r.HandleFunc("/_peer/cache/{key}", p.handleEntry).Methods("GET", "PUT", "POST", "DELETE")
+	r.HandleFunc("/_peer/entries", p.handleEntries).Methods("GET")
+	r.HandleFunc("/_peer/scan", p.handleScan).Methods("GET")
+	r.HandleFunc("/_peer/peers", p.handlePeers).Methods("GET", "PUT")
+}
+
+func (p *PeerCache) handleEntry(w http.ResponseWriter, r *http.Request) {
+	key := mux.Vars(r)["key"]
+	switch r.Method {
+	case "GET":
+		value, expiration, err := p.local.GetWithExpiration(key)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		entry, err := newWireEntry(key, value, expiration)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(entry)
+	case "PUT":
+		var entry wireEntry
+		if err := json.NewDecoder(r.Body).Decode(&entry); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		value, err := entry.value()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := p.local.Set(key, value, entry.ttl()); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	case "POST":
+		var write conditionalWrite
+		if err := json.NewDecoder(r.Body).Decode(&write); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if err := p.writeIf(key, write); err != nil {
+			status := http.StatusInternalServerError
+			switch {
+			case errors.Is(err, errPreconditionFailed), errors.Is(err, cache.ErrKeyExists):
+				status = http.StatusPreconditionFailed
+			case cache.IsCacheMiss(err):
+				status = http.StatusNotFound
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	case "DELETE":
+		if err := p.local.Delete(key); err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// writeIf applies a conditional write of another peer to the local cache.
+// Values are compared decoded, since encoding them again may not give the
+// same bytes.
+func (p *PeerCache) writeIf(key string, write conditionalWrite) error {
+	value, err := write.Entry.value()
+	if err != nil {
+		return err
+	}
+	if write.Expected == nil {
+		return p.local.Add(key, value, write.Entry.ttl())
+	}
+	expected, err := write.Expected.value()
+	if err != nil {
+		return err
+	}
+	return p.local.Update(key, func(current interface{}) (interface{}, time.Duration, error) {
+		if !reflect.DeepEqual(current, expected) {
+			return nil, 0, errPreconditionFailed
+		}
+		return value, write.Entry.ttl(), nil
+	})
+}
+
+func (p *PeerCache) handleEntries(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(p.localEntries())
+}
+
+func (p *PeerCache) handleScan(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	count, err := strconv.Atoi(query.Get("count"))
+	if err != nil {
+		http.Error(w, "Invalid count", http.StatusBadRequest)
+		return
+	}
+	page, err := p.localPage(query.Get("cursor"), query.Get("match"), count)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
+
+func (p *PeerCache) handlePeers(w http.ResponseWriter, r *http.Request) {
+	if r.Method == "PUT" {
+		var peers []string
+		if err := json.NewDecoder(r.Body).Decode(&peers); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if err := p.SetPeers(peers); err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(p.Peers())
+}
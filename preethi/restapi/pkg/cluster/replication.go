@@ -0,0 +1,516 @@
+//Leader/follower replication of the in-memory cache
+
+package cluster
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Preethi0716/Cache-Library/preethi/restapi/pkg/cache"
+	"github.com/gorilla/mux"
+)
+
+const (
+	RoleLeader   = "leader"
+	RoleFollower = "follower"
+)
+
+// heartbeatInterval is how often an idle stream tells followers the
+// leader's sequence number, and reconnectDelay how long a follower waits
+// before connecting again after losing the leader.
+const (
+	heartbeatInterval = 5 * time.Second
+	reconnectDelay    = time.Second
+)
+
+var ErrReadOnly = errors.New("cache is a read-only replication follower")
+
+var errSnapshotNeeded = errors.New("follower is too far behind the replication log")
+
+type replicationOp struct {
+	Seq   uint64    `json:"seq"`
+	Op    string    `json:"op"`
+	Time  time.Time `json:"time"`
+	Entry wireEntry `json:"entry"`
+}
+
+type replicationSnapshot struct {
+	Seq     uint64      `json:"seq"`
+	Entries []wireEntry `json:"entries"`
+}
+
+// ReplicatedCache wraps an LRUCache. On the leader every Set and Delete is
+// numbered and kept in a bounded log that followers stream over HTTP. A
+// follower loads a snapshot, then applies the streamed operations, and
+// rejects writes until it is promoted.
+type ReplicatedCache struct {
+	local  *cache.LRUCache
+	client *http.Client
+
+	mutex   sync.Mutex
+	role    string
+	leader  string
+	seq     uint64
+	log     []replicationOp
+	logSize int
+	notify  chan struct{}
+
+	// Follower progress, used to report the replication lag.
+	leaderSeq   uint64
+	lastApplied time.Time
+	lastContact time.Time
+	stop        chan struct{}
+	stopped     chan struct{}
+}
+
+// NewReplicatedLeader keeps the last logSize operations for followers that
+// reconnect; followers further behind load a new snapshot.
+func NewReplicatedLeader(local *cache.LRUCache, logSize int) *ReplicatedCache {
+	return &ReplicatedCache{
+		local:   local,
+		client:  &http.Client{},
+		role:    RoleLeader,
+		logSize: logSize,
+		notify:  make(chan struct{}),
+	}
+}
+
+// NewReplicatedFollower replicates the leader at leaderURL, such as
+// http://localhost:8080, once Start is called.
+func NewReplicatedFollower(local *cache.LRUCache, leaderURL string, logSize int) *ReplicatedCache {
+	c := NewReplicatedLeader(local, logSize)
+	c.role = RoleFollower
+	c.leader = strings.TrimRight(leaderURL, "/")
+	return c
+}
+
+// SetTransport changes how requests to the leader are sent, such as to
+// authenticate them. It is called before Start.
+func (c *ReplicatedCache) SetTransport(transport http.RoundTripper) {
+	c.client.Transport = transport
+}
+
+// Start begins tailing the leader in the background. It does nothing on a leader.
+func (c *ReplicatedCache) Start() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.role != RoleFollower || c.stop != nil {
+		return
+	}
+	c.stop = make(chan struct{})
+	c.stopped = make(chan struct{})
+	go c.follow(c.stop, c.stopped)
+}
+
+// Promote turns a follower into a leader that accepts writes, continuing
+// the sequence numbers of the operations it applied.
+func (c *ReplicatedCache) Promote() {
+	c.mutex.Lock()
+	stop, stopped := c.stop, c.stopped
+	c.stop, c.stopped = nil, nil
+	c.role = RoleLeader
+	c.leader = ""
+	c.mutex.Unlock()
+	if stop != nil {
+		close(stop)
+		<-stopped
+	}
+}
+
+func (c *ReplicatedCache) Role() string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.role
+}
+
+func (c *ReplicatedCache) Set(key string, value interface{}, ttl time.Duration) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.role != RoleLeader {
+		return ErrReadOnly
+	}
+	entry, err := newWireEntry(key, value, cache.ExpiresAt(ttl))
+	if err != nil {
+		return err
+	}
+	if err := c.local.Set(key, value, ttl); err != nil {
+		return err
+	}
+	c.append("set", entry)
+	return nil
+}
+
+// Add and Update run under the same lock as every other write, so the
+// local cache and the log see them as one step.
+func (c *ReplicatedCache) Add(key string, value interface{}, ttl time.Duration) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.role != RoleLeader {
+		return ErrReadOnly
+	}
+	entry, err := newWireEntry(key, value, cache.ExpiresAt(ttl))
+	if err != nil {
+		return err
+	}
+	if err := c.local.Add(key, value, ttl); err != nil {
+		return err
+	}
+	c.append("set", entry)
+	return nil
+}
+
+func (c *ReplicatedCache) Update(key string, fn cache.UpdateFunc) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.role != RoleLeader {
+		return ErrReadOnly
+	}
+	var entry wireEntry
+	err := c.local.Update(key, func(current interface{}) (interface{}, time.Duration, error) {
+		next, ttl, err := fn(current)
+		if err != nil {
+			return nil, 0, err
+		}
+		entry, err = newWireEntry(key, next, cache.ExpiresAt(ttl))
+		return next, ttl, err
+	})
+	if err != nil {
+		return err
+	}
+	c.append("set", entry)
+	return nil
+}
+
+func (c *ReplicatedCache) Get(key string) (interface{}, error) {
+	return c.local.Get(key)
+}
+
+func (c *ReplicatedCache) Delete(key string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.role != RoleLeader {
+		return ErrReadOnly
+	}
+	if err := c.local.Delete(key); err != nil {
+		return err
+	}
+	c.append("delete", wireEntry{Key: key})
+	return nil
+}
+
+func (c *ReplicatedCache) GetAll() (map[string]interface{}, error) {
+	return c.local.GetAll()
+}
+
+func (c *ReplicatedCache) Scan(cursor string, match string, count int) (map[string]interface{}, string, error) {
+	return c.local.Scan(cursor, match, count)
+}
+
+func (c *ReplicatedCache) Expirations(keys []string) (map[string]time.Time, error) {
+	return c.local.Expirations(keys)
+}
+
+func (c *ReplicatedCache) Stats() map[string]interface{} {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	replication := map[string]interface{}{
+		"role": c.role,
+		"seq":  c.seq,
+	}
+	if c.role == RoleFollower {
+		lagOps := uint64(0)
+		if c.leaderSeq > c.seq {
+			lagOps = c.leaderSeq - c.seq
+		}
+		replication["leader"] = c.leader
+		replication["leader_seq"] = c.leaderSeq
+		replication["lag_ops"] = lagOps
+		if !c.lastApplied.IsZero() {
+			replication["last_applied_op_age"] = time.Since(c.lastApplied).String()
+		}
+		if !c.lastContact.IsZero() {
+			replication["last_contact"] = c.lastContact
+		}
+	}
+	stats := c.local.Stats()
+	stats["replication"] = replication
+	return stats
+}
+
+// append must be called with the mutex held.
+func (c *ReplicatedCache) append(op string, entry wireEntry) {
+	c.seq++
+	c.log = append(c.log, replicationOp{Seq: c.seq, Op: op, Time: time.Now(), Entry: entry})
+	if len(c.log) > c.logSize {
+		c.log = c.log[len(c.log)-c.logSize:]
+	}
+	// Wake up every stream waiting for new operations.
+	close(c.notify)
+	c.notify = make(chan struct{})
+}
+
+// opsSince returns the logged operations after seq, or errSnapshotNeeded
+// when some of them were already dropped from the log.
+func (c *ReplicatedCache) opsSince(seq uint64) ([]replicationOp, uint64, <-chan struct{}, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if seq > c.seq {
+		return nil, 0, nil, errSnapshotNeeded
+	}
+	if seq < c.seq && (len(c.log) == 0 || c.log[0].Seq > seq+1) {
+		return nil, 0, nil, errSnapshotNeeded
+	}
+	var ops []replicationOp
+	for _, op := range c.log {
+		if op.Seq > seq {
+			ops = append(ops, op)
+		}
+	}
+	return ops, c.seq, c.notify, nil
+}
+
+func (c *ReplicatedCache) snapshot() replicationSnapshot {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	snapshot := replicationSnapshot{Seq: c.seq, Entries: []wireEntry{}}
+	for _, item := range c.local.Items() {
+		if entry, err := newWireEntry(item.Key(), item.Value(), item.Expiration()); err == nil {
+			snapshot.Entries = append(snapshot.Entries, entry)
+		}
+	}
+	return snapshot
+}
+
+// RegisterRoutes adds the endpoints followers and operators call to r.
+func (c *ReplicatedCache) RegisterRoutes(r *mux.Router) {
+	r.HandleFunc("/_replication/snapshot", c.handleSnapshot).Methods("GET")
+	r.HandleFunc("/_replication/stream", c.handleStream).Methods("GET")
+	r.HandleFunc("/_replication/promote", c.handlePromote).Methods("POST")
+}
+
+func (c *ReplicatedCache) handleSnapshot(w http.ResponseWriter, r *http.Request) {
+	if c.Role() != RoleLeader {
+		http.Error(w, "not the replication leader", http.StatusConflict)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(c.snapshot())
+}
+
+// handleStream sends one JSON operation per line, starting after the
+// sequence number given in "from", and heartbeats while there is nothing
+// new so followers learn the leader's position.
+func (c *ReplicatedCache) handleStream(w http.ResponseWriter, r *http.Request) {
+	if c.Role() != RoleLeader {
+		http.Error(w, "not the replication leader", http.StatusConflict)
+		return
+	}
+	from, err := strconv.ParseUint(r.URL.Query().Get("from"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid from sequence", http.StatusBadRequest)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-ndjson")
+
+	encoder := json.NewEncoder(w)
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		ops, seq, notify, err := c.opsSince(from)
+		if err != nil {
+			// Headers may already be sent, so the follower learns about the
+			// gap from the sequence numbers instead of the status code.
+			encoder.Encode(replicationOp{Seq: seq, Op: "snapshot"})
+			flusher.Flush()
+			return
+		}
+		for _, op := range ops {
+			if err := encoder.Encode(op); err != nil {
+				return
+			}
+			from = op.Seq
+		}
+		flusher.Flush()
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-notify:
+		case <-heartbeat.C:
+			if err := encoder.Encode(replicationOp{Seq: seq, Op: "heartbeat", Time: time.Now()}); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+func (c *ReplicatedCache) handlePromote(w http.ResponseWriter, r *http.Request) {
+	c.Promote()
+	w.WriteHeader(http.StatusOK)
+}
+
+func (c *ReplicatedCache) follow(stop, stopped chan struct{}) {
+	defer close(stopped)
+	needSnapshot := true
+	for {
+		var err error
+		if needSnapshot {
+			err = c.bootstrap()
+		}
+		if err == nil {
+			err = c.tail(stop)
+		}
+		needSnapshot = errors.Is(err, errSnapshotNeeded) || (needSnapshot && err != nil)
+		select {
+		case <-stop:
+			return
+		case <-time.After(reconnectDelay):
+		}
+	}
+}
+
+// leaderURL returns the leader to follow, or an error once promoted.
+func (c *ReplicatedCache) leaderURL() (string, uint64, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.role != RoleFollower {
+		return "", 0, errors.New("no longer a replication follower")
+	}
+	return c.leader, c.seq, nil
+}
+
+// bootstrap replaces the local contents with a snapshot of the leader.
+func (c *ReplicatedCache) bootstrap() error {
+	leader, _, err := c.leaderURL()
+	if err != nil {
+		return err
+	}
+	resp, err := c.client.Get(leader + "/_replication/snapshot")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("leader answered %s", resp.Status)
+	}
+	var snapshot replicationSnapshot
+	if err := json.NewDecoder(resp.Body).Decode(&snapshot); err != nil {
+		return err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.role != RoleFollower {
+		return nil
+	}
+	c.local.Flush()
+	// Entries come most recently used first, so set them in reverse to keep
+	// the leader's eviction order.
+	for i := len(snapshot.Entries) - 1; i >= 0; i-- {
+		entry := snapshot.Entries[i]
+		value, err := entry.value()
+		if err != nil {
+			return err
+		}
+		c.local.Set(entry.Key, value, entry.ttl())
+	}
+	c.seq = snapshot.Seq
+	c.leaderSeq = snapshot.Seq
+	c.lastContact = time.Now()
+	return nil
+}
+
+// tail applies the leader's operations until the stream ends or stop is closed.
+func (c *ReplicatedCache) tail(stop chan struct{}) error {
+	leader, from, err := c.leaderURL()
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/_replication/stream?from=%d", leader, from), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("leader answered %s", resp.Status)
+	}
+	go func() {
+		<-stop
+		resp.Body.Close()
+	}()
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		var op replicationOp
+		if err := json.Unmarshal(scanner.Bytes(), &op); err != nil {
+			return err
+		}
+		if err := c.apply(op); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return errors.New("replication stream closed")
+}
+
+func (c *ReplicatedCache) apply(op replicationOp) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.role != RoleFollower {
+		return errors.New("no longer a replication follower")
+	}
+	c.lastContact = time.Now()
+	if op.Seq > c.leaderSeq {
+		c.leaderSeq = op.Seq
+	}
+	switch op.Op {
+	case "heartbeat":
+		return nil
+	case "snapshot":
+		return errSnapshotNeeded
+	}
+	if op.Seq <= c.seq {
+		return nil
+	}
+	if op.Seq != c.seq+1 {
+		return errSnapshotNeeded
+	}
+	switch op.Op {
+	case "set":
+		value, err := op.Entry.value()
+		if err != nil {
+			return err
+		}
+		c.local.Set(op.Entry.Key, value, op.Entry.ttl())
+	case "delete":
+		c.local.Delete(op.Entry.Key)
+	}
+	c.seq = op.Seq
+	c.lastApplied = op.Time
+	// Keep a log on followers too, so a promoted follower can serve others.
+	c.log = append(c.log, op)
+	if len(c.log) > c.logSize {
+		c.log = c.log[len(c.log)-c.logSize:]
+	}
+	return nil
+}
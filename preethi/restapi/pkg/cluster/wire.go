@@ -0,0 +1,41 @@
+//Wire format for cache entries exchanged between instances
+
+package cluster
+
+import (
+	"time"
+
+	"github.com/Preethi0716/Cache-Library/preethi/restapi/pkg/cache"
+)
+
+type wireEntry struct {
+	Key       string    `json:"key"`
+	Codec     byte      `json:"codec"`
+	Data      []byte    `json:"data"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func newWireEntry(key string, value interface{}, expiresAt time.Time) (wireEntry, error) {
+	data, codec, err := cache.EncodeValue(cache.GobCodec{}, value)
+	if err != nil {
+		return wireEntry{}, err
+	}
+	return wireEntry{Key: key, Codec: codec, Data: data, ExpiresAt: expiresAt}, nil
+}
+
+func (e wireEntry) value() (interface{}, error) {
+	return cache.DecodeValue(e.Data, e.Codec)
+}
+
+// ttl is the time the entry has left, zero if it does not expire. An
+// expired entry gets the shortest ttl, so it is gone right away.
+func (e wireEntry) ttl() time.Duration {
+	if e.ExpiresAt.IsZero() {
+		return 0
+	}
+	ttl := time.Until(e.ExpiresAt)
+	if ttl <= 0 {
+		return time.Nanosecond
+	}
+	return ttl
+}
@@ -0,0 +1,306 @@
+//Token bucket rate limits and daily quotas per client
+
+package ratelimit
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Preethi0716/Cache-Library/preethi/restapi/pkg/cache"
+)
+
+// keyPrefix starts every key the limiter stores.
+const keyPrefix = "ratelimit:"
+
+// addAttempts bounds how often a bucket is created and updated again when
+// it is evicted between reading and writing it.
+const addAttempts = 3
+
+// ErrUnreachable means a shared store could not be reached, and the
+// limiter falls back to counting on its own.
+var ErrUnreachable = errors.New("rate limit store is unreachable")
+
+// Rate lets Requests through per Per on average, and up to Burst at once.
+// The zero Rate does not limit.
+type Rate struct {
+	Requests int
+	Per      time.Duration
+	Burst    int
+}
+
+// ParseRate reads a rate such as 100/s, 3000/m or 50/10s, optionally with
+// a burst as in 100/s:200. The burst defaults to the requests.
+func ParseRate(value string) (Rate, error) {
+	if value == "" || value == "0" {
+		return Rate{}, nil
+	}
+	spec, burst, hasBurst := strings.Cut(value, ":")
+	count, per, found := strings.Cut(spec, "/")
+	if !found {
+		return Rate{}, fmt.Errorf("%q is not a rate such as 100/s or 3000/m:500", value)
+	}
+	var rate Rate
+	var err error
+	if rate.Requests, err = strconv.Atoi(count); err != nil || rate.Requests <= 0 {
+		return Rate{}, fmt.Errorf("%q does not start with a positive number of requests", value)
+	}
+	switch per {
+	case "s":
+		rate.Per = time.Second
+	case "m":
+		rate.Per = time.Minute
+	case "h":
+		rate.Per = time.Hour
+	default:
+		if rate.Per, err = time.ParseDuration(per); err != nil || rate.Per <= 0 {
+			return Rate{}, fmt.Errorf("%q is not per s, m, h or a duration such as 10s", value)
+		}
+	}
+	rate.Burst = rate.Requests
+	if hasBurst {
+		if rate.Burst, err = strconv.Atoi(burst); err != nil || rate.Burst <= 0 {
+			return Rate{}, fmt.Errorf("%q does not end with a positive burst", value)
+		}
+	}
+	return rate, nil
+}
+
+func (r Rate) String() string {
+	if r.Requests == 0 {
+		return "0"
+	}
+	return fmt.Sprintf("%d/%v:%d", r.Requests, r.Per, r.Burst)
+}
+
+func (r Rate) perSecond() float64 {
+	return float64(r.Requests) / r.Per.Seconds()
+}
+
+// Policy holds the limits of every client. Reads are GET and HEAD
+// requests, writes all others. DailyQuota bounds the requests of a client
+// per UTC day, with 0 for no quota.
+type Policy struct {
+	Reads      Rate
+	Writes     Rate
+	DailyQuota int
+}
+
+// Enabled reports whether p limits anything.
+func (p Policy) Enabled() bool {
+	return p.Reads.Requests > 0 || p.Writes.Requests > 0 || p.DailyQuota > 0
+}
+
+// Result describes a limit after a request was counted against it.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is when the limit is fully available again.
+	Reset time.Duration
+	// RetryAfter is how long a rejected client has to wait.
+	RetryAfter time.Duration
+}
+
+// Store counts requests against the buckets and quota counters kept under
+// keys. Each count is a single step, so instances sharing a store never
+// race on a key.
+type Store interface {
+	// TakeToken refills the bucket at key for the time since its last
+	// update and takes a token from it if there is one.
+	TakeToken(key string, rate Rate, now time.Time) (Result, error)
+	// CountQuota counts a request in the counter at key, which lasts until
+	// the end of the UTC day.
+	CountQuota(key string, quota int, now time.Time) (Result, error)
+}
+
+// Limiter keeps the buckets and quota counters in a store: a local one
+// limits each instance on its own, while a shared one such as Redis makes
+// the limits hold across instances. While the shared store is unreachable,
+// the instance falls back to limiting on its own.
+type Limiter struct {
+	policy   Policy
+	store    Store
+	fallback Store
+}
+
+func NewLimiter(policy Policy, store, fallback Store) *Limiter {
+	return &Limiter{policy: policy, store: store, fallback: fallback}
+}
+
+func (l *Limiter) Policy() Policy {
+	return l.policy
+}
+
+// Allow counts a request of client against its rate and then its quota.
+// The quota result is nil without a daily quota, and the rate result is
+// nil when the kind of request is not limited.
+func (l *Limiter) Allow(client string, write bool) (rate *Result, quota *Result, err error) {
+	kind, limit := "read", l.policy.Reads
+	if write {
+		kind, limit = "write", l.policy.Writes
+	}
+	now := time.Now()
+	if limit.Requests > 0 {
+		result, err := l.withFallback(func(store Store) (Result, error) {
+			return store.TakeToken(keyPrefix+kind+":"+client, limit, now)
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		rate = &result
+		if !result.Allowed {
+			return rate, nil, nil
+		}
+	}
+	if l.policy.DailyQuota > 0 {
+		result, err := l.withFallback(func(store Store) (Result, error) {
+			return store.CountQuota(keyPrefix+"quota:"+client+":"+now.UTC().Format("2006-01-02"), l.policy.DailyQuota, now)
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		quota = &result
+	}
+	return rate, quota, nil
+}
+
+func (l *Limiter) withFallback(take func(store Store) (Result, error)) (Result, error) {
+	result, err := take(l.store)
+	if errors.Is(err, ErrUnreachable) && l.fallback != nil {
+		return take(l.fallback)
+	}
+	return result, err
+}
+
+// LocalStore keeps the limits of one instance in a cache, such as an LRU
+// bounding the clients it remembers.
+type LocalStore struct {
+	mutex sync.Mutex
+	cache cache.Cache
+}
+
+func NewLocalStore(c cache.Cache) *LocalStore {
+	return &LocalStore{cache: c}
+}
+
+// bucket is stored as "<tokens> <unix nanoseconds of the last update>".
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+func parseBucket(value interface{}) (bucket, error) {
+	text, ok := value.(string)
+	if !ok {
+		return bucket{}, fmt.Errorf("unexpected bucket %T", value)
+	}
+	tokens, updated, _ := strings.Cut(text, " ")
+	b := bucket{}
+	var err error
+	if b.tokens, err = strconv.ParseFloat(tokens, 64); err != nil {
+		return bucket{}, fmt.Errorf("invalid bucket %q", text)
+	}
+	nanos, err := strconv.ParseInt(updated, 10, 64)
+	if err != nil {
+		return bucket{}, fmt.Errorf("invalid bucket %q", text)
+	}
+	b.updated = time.Unix(0, nanos)
+	return b, nil
+}
+
+func (b bucket) String() string {
+	return strconv.FormatFloat(b.tokens, 'f', -1, 64) + " " + strconv.FormatInt(b.updated.UnixNano(), 10)
+}
+
+func (s *LocalStore) TakeToken(key string, rate Rate, now time.Time) (Result, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	var result Result
+	err := change(s.cache, key, bucket{tokens: float64(rate.Burst), updated: now}.String(), func(current interface{}) (interface{}, time.Duration, error) {
+		b, err := parseBucket(current)
+		if err != nil {
+			return nil, 0, err
+		}
+		if elapsed := now.Sub(b.updated); elapsed > 0 {
+			b.tokens = math.Min(float64(rate.Burst), b.tokens+elapsed.Seconds()*rate.perSecond())
+			b.updated = now
+		}
+		allowed := b.tokens >= 1
+		if allowed {
+			b.tokens--
+		}
+		result = bucketResult(rate, b.tokens, allowed)
+		// A bucket that would be full again is the same as none.
+		return b.String(), result.Reset + time.Second, nil
+	})
+	return result, err
+}
+
+func (s *LocalStore) CountQuota(key string, quota int, now time.Time) (Result, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	var result Result
+	err := change(s.cache, key, "0", func(current interface{}) (interface{}, time.Duration, error) {
+		text, _ := current.(string)
+		count, err := strconv.Atoi(text)
+		if err != nil {
+			return nil, 0, fmt.Errorf("invalid quota counter %q", text)
+		}
+		allowed := count < quota
+		if allowed {
+			count++
+		}
+		result = quotaResult(quota, count, allowed, now)
+		return strconv.Itoa(count), result.Reset, nil
+	})
+	return result, err
+}
+
+// bucketResult describes a bucket left with tokens after a request.
+func bucketResult(rate Rate, tokens float64, allowed bool) Result {
+	result := Result{Allowed: allowed, Limit: rate.Burst, Remaining: int(tokens)}
+	if !allowed {
+		result.RetryAfter = seconds((1 - tokens) / rate.perSecond())
+	}
+	result.Reset = seconds((float64(rate.Burst) - tokens) / rate.perSecond())
+	return result
+}
+
+// quotaResult describes a quota counter that reached count.
+func quotaResult(quota, count int, allowed bool, now time.Time) Result {
+	result := Result{Allowed: allowed, Limit: quota, Remaining: max(quota-count, 0), Reset: endOfDay(now).Sub(now)}
+	if !allowed {
+		result.RetryAfter = result.Reset
+	}
+	return result
+}
+
+// endOfDay is when the quota counters of now start over.
+func endOfDay(now time.Time) time.Time {
+	return now.UTC().Truncate(24 * time.Hour).Add(24 * time.Hour)
+}
+
+// change updates key with fn, creating it with initial first.
+func change(store cache.Cache, key string, initial string, fn cache.UpdateFunc) error {
+	for attempt := 0; attempt < addAttempts; attempt++ {
+		err := cache.Update(store, key, fn)
+		if !cache.IsCacheMiss(err) {
+			return err
+		}
+		err = cache.Add(store, key, initial, time.Minute)
+		if err != nil && !errors.Is(err, cache.ErrKeyExists) {
+			return err
+		}
+	}
+	return cache.ErrUpdateConflict
+}
+
+// seconds rounds up to whole seconds, as used in the headers.
+func seconds(s float64) time.Duration {
+	return time.Duration(math.Ceil(s)) * time.Second
+}
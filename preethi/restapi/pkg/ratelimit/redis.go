@@ -0,0 +1,146 @@
+//Rate limits shared by every instance through Redis
+
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// redisTimeout bounds every call to Redis, which is made for each request.
+const redisTimeout = time.Second
+
+// redisRetryInterval is how long an unreachable Redis is skipped before it
+// is tried again.
+const redisRetryInterval = 5 * time.Second
+
+// takeTokenScript refills and takes from a bucket stored as
+// "<tokens> <unix microseconds of the last update>", replying whether a
+// token was taken and the tokens left. ARGV holds the burst, the tokens
+// added per second and the current time in unix microseconds. A bucket
+// expires once it would be full again, since that is the same as none.
+var takeTokenScript = redis.NewScript(`
+local burst = tonumber(ARGV[1])
+local per_second = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local tokens, updated = burst, now
+local stored = redis.call('GET', KEYS[1])
+if stored then
+	local t, u = string.match(stored, '^(%S+) (%S+)$')
+	tokens, updated = tonumber(t), tonumber(u)
+	if now > updated then
+		tokens = math.min(burst, tokens + (now - updated) / 1000000 * per_second)
+		updated = now
+	end
+end
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+local full = math.ceil((burst - tokens) / per_second) * 1000 + 1000
+redis.call('SET', KEYS[1], string.format('%.17g %d', tokens, updated), 'PX', full)
+return {allowed, string.format('%.17g', tokens)}
+`)
+
+// RedisStore shares the limits through Redis. Every count is a single
+// round trip that is never retried, so a lost reply cannot count a request
+// twice. Replies that do not arrive report ErrUnreachable, and Redis is then
+// skipped for a while so an outage does not slow every request down.
+type RedisStore struct {
+	client *redis.Client
+
+	mutex     sync.Mutex
+	downUntil time.Time
+}
+
+// NewRedisStore counts in database db of the Redis server at addr, which
+// should not hold cache entries: clients allowed to delete them could
+// otherwise reset their own limits.
+func NewRedisStore(addr string, db int) *RedisStore {
+	return &RedisStore{client: redis.NewClient(&redis.Options{
+		Addr:         addr,
+		DB:           db,
+		MaxRetries:   -1,
+		DialTimeout:  redisTimeout,
+		ReadTimeout:  redisTimeout,
+		WriteTimeout: redisTimeout,
+	})}
+}
+
+func (s *RedisStore) Ping() error {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	return s.client.Ping(ctx).Err()
+}
+
+func (s *RedisStore) Close() error {
+	return s.client.Close()
+}
+
+func (s *RedisStore) TakeToken(key string, rate Rate, now time.Time) (Result, error) {
+	var reply []interface{}
+	err := s.call(func(ctx context.Context) (err error) {
+		reply, err = takeTokenScript.Run(ctx, s.client, []string{key}, rate.Burst, rate.perSecond(), now.UnixMicro()).Slice()
+		return err
+	})
+	if err != nil {
+		return Result{}, err
+	}
+	if len(reply) != 2 {
+		return Result{}, fmt.Errorf("unexpected bucket reply %v", reply)
+	}
+	allowed, _ := reply[0].(int64)
+	text, _ := reply[1].(string)
+	tokens, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return Result{}, fmt.Errorf("invalid bucket tokens %q", text)
+	}
+	return bucketResult(rate, tokens, allowed == 1), nil
+}
+
+func (s *RedisStore) CountQuota(key string, quota int, now time.Time) (Result, error) {
+	var count *redis.IntCmd
+	err := s.call(func(ctx context.Context) error {
+		_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			count = pipe.Incr(ctx, key)
+			pipe.ExpireAt(ctx, key, endOfDay(now))
+			return nil
+		})
+		return err
+	})
+	if err != nil {
+		return Result{}, err
+	}
+	counted := int(count.Val())
+	return quotaResult(quota, counted, counted <= quota, now), nil
+}
+
+// call runs fn against Redis unless it was found unreachable recently.
+// Errors Redis replies with mean it was reached and are returned as is.
+func (s *RedisStore) call(fn func(ctx context.Context) error) error {
+	s.mutex.Lock()
+	down := time.Now().Before(s.downUntil)
+	s.mutex.Unlock()
+	if down {
+		return ErrUnreachable
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	err := fn(ctx)
+	var replyErr redis.Error
+	if err == nil || errors.As(err, &replyErr) {
+		return err
+	}
+	s.mutex.Lock()
+	s.downUntil = time.Now().Add(redisRetryInterval)
+	s.mutex.Unlock()
+	return fmt.Errorf("%w: %v", ErrUnreachable, err)
+}
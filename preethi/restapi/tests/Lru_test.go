@@ -159,3 +159,60 @@ func TestLRUCache_DifferentPorts(t *testing.T) {
 		})
 	}
 }
+
+func TestLRUCache_Resize(t *testing.T) {
+	cache := cache.NewLRUCache(3)
+	cache.Set("key1", "value1", time.Minute)
+	cache.Set("key2", "value2", time.Minute)
+	cache.Set("key3", "value3", time.Minute)
+	cache.Get("key1")
+
+	// Shrinking evicts the least recently used entries.
+	cache.Resize(2)
+	if _, err := cache.Get("key2"); err == nil {
+		t.Fatal("Expected key2 to be evicted when shrinking")
+	}
+	for _, key := range []string{"key1", "key3"} {
+		if _, err := cache.Get(key); err != nil {
+			t.Fatalf("Expected %v to survive shrinking: %v", key, err)
+		}
+	}
+
+	cache.Resize(3)
+	cache.Set("key4", "value4", time.Minute)
+	if entries := cache.Stats()["entries"]; entries != 3 {
+		t.Fatalf("Expected 3 entries after growing, got %v", entries)
+	}
+}
+
+func TestLRUCache_Scan(t *testing.T) {
+	cache := cache.NewLRUCache(10)
+	for i := 0; i < 5; i++ {
+		cache.Set(fmt.Sprintf("key%d", i), i, time.Minute)
+	}
+	cache.Set("other", "value", time.Minute)
+	cache.Set("expired", "value", time.Nanosecond)
+	time.Sleep(time.Millisecond)
+
+	var keys []string
+	cursor := ""
+	for pages := 0; pages < 10; pages++ {
+		entries, next, err := cache.Scan(cursor, "key*", 2)
+		if err != nil {
+			t.Fatalf("Failed to scan: %v", err)
+		}
+		if len(entries) > 2 {
+			t.Fatalf("Expected at most 2 entries per page, got %v", entries)
+		}
+		for key := range entries {
+			keys = append(keys, key)
+		}
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+	if len(keys) != 5 {
+		t.Errorf("Expected the 5 matching keys, got %v", keys)
+	}
+}
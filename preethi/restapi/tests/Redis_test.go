@@ -1,12 +1,14 @@
 package tests
 
 import (
+	"context"
 	"fmt"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/Preethi0716/Cache-Library/preethi/restapi/pkg/cache"
+	"github.com/go-redis/redis/v8"
 )
 
 func TestRedisCache_SetGet(t *testing.T) {
@@ -139,3 +141,194 @@ func TestRedisCache_UpdateValue(t *testing.T) {
 		t.Fatalf("Expected updatedValue, got %v", value)
 	}
 }
+
+func TestRedisCache_TypedValues(t *testing.T) {
+	c, err := cache.NewRedisCache("localhost:6379", cache.WithCodec(cache.GobCodec{}))
+	if err != nil {
+		t.Fatalf("Failed to create Redis cache: %v", err)
+	}
+
+	values := map[string]interface{}{
+		"typed:string": "value1",
+		"typed:bytes":  []byte{0, 1, 2},
+		"typed:int":    42,
+		"typed:struct": codecTestValue{Name: "value1", Count: 2},
+	}
+	for key, want := range values {
+		if err := c.Set(key, want, time.Minute); err != nil {
+			t.Fatalf("Failed to set %v: %v", key, err)
+		}
+		got, err := c.Get(key)
+		if err != nil {
+			t.Fatalf("Failed to get %v: %v", key, err)
+		}
+		if fmt.Sprintf("%T %v", got, got) != fmt.Sprintf("%T %v", want, want) {
+			t.Fatalf("Expected %T %v for %v, got %T %v", want, want, key, got, got)
+		}
+	}
+}
+
+func TestRedisCache_LegacyValues(t *testing.T) {
+	c, err := cache.NewRedisCache("localhost:6379")
+	if err != nil {
+		t.Fatalf("Failed to create Redis cache: %v", err)
+	}
+
+	// Text written before codecs were introduced has no header byte.
+	client := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+	defer client.Close()
+	ctx := context.Background()
+	values := map[string]string{
+		"legacy:tab":     "\tindented",
+		"legacy:newline": "\nline",
+		"legacy:control": "\x03text",
+		"legacy:utf8":    "\u00e9t\u00e9",
+	}
+	for key, value := range values {
+		if err := client.Set(ctx, key, value, time.Minute).Err(); err != nil {
+			t.Fatalf("Failed to set %v: %v", key, err)
+		}
+		defer client.Del(ctx, key)
+	}
+	for key, want := range values {
+		got, err := c.Get(key)
+		if err != nil || got != want {
+			t.Fatalf("Expected %q for %v, got %q, %v", want, key, got, err)
+		}
+	}
+}
+
+func TestRedisCache_ScanAndGetAll(t *testing.T) {
+	c, err := cache.NewRedisCache("localhost:6379")
+	if err != nil {
+		t.Fatalf("Failed to create Redis cache: %v", err)
+	}
+
+	for i := 0; i < 25; i++ {
+		if err := c.Set(fmt.Sprintf("scan:%d", i), fmt.Sprintf("value%d", i), time.Minute); err != nil {
+			t.Fatalf("Failed to set value: %v", err)
+		}
+	}
+
+	found := make(map[string]interface{})
+	cursor := ""
+	for {
+		entries, next, err := c.Scan(cursor, "scan:*", 10)
+		if err != nil {
+			t.Fatalf("Failed to scan: %v", err)
+		}
+		for key, value := range entries {
+			found[key] = value
+		}
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+	if len(found) != 25 || found["scan:7"] != "value7" {
+		t.Fatalf("Expected 25 scanned entries, got %d", len(found))
+	}
+
+	all, err := c.GetAll()
+	if err != nil {
+		t.Fatalf("Failed to get all entries: %v", err)
+	}
+	if all["scan:7"] != "value7" {
+		t.Fatalf("Expected value7 in GetAll, got %v", all["scan:7"])
+	}
+
+	// Keys other clients wrote in other types or formats are skipped.
+	client := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+	defer client.Close()
+	ctx := context.Background()
+	client.Del(ctx, "scan:list")
+	if err := client.RPush(ctx, "scan:list", "a", "b").Err(); err != nil {
+		t.Fatalf("Failed to push list: %v", err)
+	}
+	if err := client.Set(ctx, "scan:json", []byte{0x80 | cache.CodecJSON, '{'}, time.Minute).Err(); err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+	defer client.Del(ctx, "scan:list", "scan:json")
+	all, err = c.GetAll()
+	if err != nil {
+		t.Fatalf("Expected unreadable keys to be skipped, got %v", err)
+	}
+	if _, found := all["scan:list"]; found || all["scan:7"] != "value7" {
+		t.Fatalf("Expected the readable entries only, got %v", all)
+	}
+}
+
+func TestRedisCache_Expirations(t *testing.T) {
+	c, err := cache.NewRedisCache("localhost:6379")
+	if err != nil {
+		t.Fatalf("Failed to create Redis cache: %v", err)
+	}
+	c.Set("expiry:ttl", "value", time.Minute)
+	c.Set("expiry:none", "value", 0)
+	c.Delete("expiry:missing")
+
+	expirations, err := c.Expirations([]string{"expiry:ttl", "expiry:none", "expiry:missing"})
+	if err != nil {
+		t.Fatalf("Failed to read expirations: %v", err)
+	}
+	if until := time.Until(expirations["expiry:ttl"]); until <= 50*time.Second || until > time.Minute {
+		t.Errorf("Expected expiry:ttl to expire in a minute, got %v", expirations["expiry:ttl"])
+	}
+	if expiresAt, found := expirations["expiry:none"]; !found || !expiresAt.IsZero() {
+		t.Errorf("Expected no expiry for expiry:none, got %v %v", expiresAt, found)
+	}
+	if _, found := expirations["expiry:missing"]; found {
+		t.Error("Expected the missing key to be left out")
+	}
+}
+
+func TestRedisCache_BatchOperations(t *testing.T) {
+	c, err := cache.NewRedisCache("localhost:6379")
+	if err != nil {
+		t.Fatalf("Failed to create Redis cache: %v", err)
+	}
+	testRedisBatchOperations(t, c)
+}
+
+func TestRedisCache_TypedValue(t *testing.T) {
+	c, err := cache.NewRedisCache("localhost:6379")
+	if err != nil {
+		t.Fatalf("Failed to create Redis cache: %v", err)
+	}
+
+	typed := cache.TypedValue{ContentType: "application/json", Data: []byte(`{"name":"value1"}`)}
+	if err := c.Set("typed1", typed, time.Minute); err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+	value, err := c.Get("typed1")
+	decoded, ok := value.(cache.TypedValue)
+	if err != nil || !ok || decoded.ContentType != typed.ContentType || string(decoded.Data) != string(typed.Data) {
+		t.Fatalf("Expected %#v, got %#v (error: %v)", typed, value, err)
+	}
+}
+
+func testRedisBatchOperations(t *testing.T, c *cache.RedisCache) {
+	entries := make(map[string]interface{})
+	keys := make([]string, 0)
+	for i := 0; i < 20; i++ {
+		key := fmt.Sprintf("batch:%d", i)
+		entries[key] = fmt.Sprintf("value%d", i)
+		keys = append(keys, key)
+	}
+	if err := c.SetMulti(entries, time.Minute); err != nil {
+		t.Fatalf("Failed to set entries: %v", err)
+	}
+
+	values, err := c.GetMulti(append(keys, "batch:missing"))
+	if err != nil || len(values) != 20 || values["batch:3"] != "value3" {
+		t.Fatalf("Expected 20 values, got %v (error: %v)", values, err)
+	}
+
+	deleted, err := c.DeleteMatching("batch:*")
+	if err != nil || deleted != 20 {
+		t.Fatalf("Expected 20 deleted keys, got %d (error: %v)", deleted, err)
+	}
+	if _, err := c.Get("batch:3"); err == nil {
+		t.Fatal("Expected an error for a deleted key")
+	}
+}
@@ -0,0 +1,188 @@
+//The Sentinel and Cluster tests start their own servers with redis-server,
+//redis-sentinel and redis-cli from PATH (Redis 5 or later), on free ports
+//in a temporary directory, and are skipped when the binaries are missing.
+//To run them against servers that are already up instead:
+//
+//REDIS_CLUSTER_ADDRS=localhost:7000,localhost:7001,localhost:7002 go test ./tests/ -run Cluster -v
+//REDIS_SENTINEL_ADDRS=localhost:26379 REDIS_SENTINEL_MASTER=mymaster go test ./tests/ -run Sentinel -v
+
+package tests
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Preethi0716/Cache-Library/preethi/restapi/pkg/cache"
+	"github.com/go-redis/redis/v8"
+)
+
+func TestRedisCache_Cluster(t *testing.T) {
+	addrs := os.Getenv("REDIS_CLUSTER_ADDRS")
+	if addrs == "" {
+		addrs = strings.Join(startRedisCluster(t), ",")
+	}
+	c, err := cache.NewRedisCacheWithConfig(cache.RedisConfig{
+		Topology: cache.RedisCluster,
+		Addrs:    strings.Split(addrs, ","),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create Redis cluster cache: %v", err)
+	}
+
+	for i := 0; i < 100; i++ {
+		if err := c.Set(fmt.Sprintf("cluster:%d", i), "value", time.Minute); err != nil {
+			t.Fatalf("Failed to set value: %v", err)
+		}
+	}
+
+	// Keys are spread over all masters, so GetAll has to visit every shard.
+	all, err := c.GetAll()
+	if err != nil {
+		t.Fatalf("Failed to get all entries: %v", err)
+	}
+	for i := 0; i < 100; i++ {
+		if all[fmt.Sprintf("cluster:%d", i)] != "value" {
+			t.Fatalf("Expected cluster:%d in GetAll", i)
+		}
+	}
+
+	testRedisBatchOperations(t, c)
+}
+
+func TestRedisCache_Sentinel(t *testing.T) {
+	addrs, master := os.Getenv("REDIS_SENTINEL_ADDRS"), os.Getenv("REDIS_SENTINEL_MASTER")
+	if addrs == "" {
+		addrs, master = startRedisSentinel(t), "mymaster"
+	}
+	c, err := cache.NewRedisCacheWithConfig(cache.RedisConfig{
+		Topology:   cache.RedisSentinel,
+		Addrs:      strings.Split(addrs, ","),
+		MasterName: master,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create Redis sentinel cache: %v", err)
+	}
+
+	if err := c.Set("sentinel:key1", "value1", time.Minute); err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+	value, err := c.Get("sentinel:key1")
+	if err != nil || value != "value1" {
+		t.Fatalf("Expected value1, got %v", value)
+	}
+}
+
+func TestRedisCache_InvalidTopology(t *testing.T) {
+	if _, err := cache.NewRedisCacheWithConfig(cache.RedisConfig{Topology: "ring", Addrs: []string{"localhost:6379"}}); err == nil {
+		t.Fatal("Expected an error for an unknown topology")
+	}
+	if _, err := cache.NewRedisCacheWithConfig(cache.RedisConfig{Topology: cache.RedisSentinel, Addrs: []string{"localhost:26379"}}); err == nil {
+		t.Fatal("Expected an error for a sentinel topology without master name")
+	}
+}
+
+// startRedisCluster starts three masters without replicas and joins them
+// into a cluster, returning their addresses.
+func startRedisCluster(t *testing.T) []string {
+	requireBinaries(t, "redis-server", "redis-cli")
+	dir := t.TempDir()
+	addrs := make([]string, 3)
+	for i := range addrs {
+		port := freePort(t)
+		addrs[i] = "127.0.0.1:" + port
+		startProcess(t, "redis-server", "--port", port, "--save", "", "--appendonly", "no", "--dir", dir,
+			"--cluster-enabled", "yes", "--cluster-config-file", "nodes-"+port+".conf")
+		waitForRedis(t, addrs[i])
+	}
+	create := exec.Command("redis-cli", append(append([]string{"--cluster", "create"}, addrs...), "--cluster-replicas", "0", "--cluster-yes")...)
+	if output, err := create.CombinedOutput(); err != nil {
+		t.Fatalf("Failed to create cluster: %v\n%s", err, output)
+	}
+	client := redis.NewClient(&redis.Options{Addr: addrs[0]})
+	defer client.Close()
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		info, err := client.ClusterInfo(context.Background()).Result()
+		if err == nil && strings.Contains(info, "cluster_state:ok") {
+			return addrs
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Cluster did not become ready: %v %s", err, info)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// startRedisSentinel starts a master and a sentinel monitoring it as
+// mymaster, returning the address of the sentinel.
+func startRedisSentinel(t *testing.T) string {
+	requireBinaries(t, "redis-server", "redis-sentinel")
+	dir := t.TempDir()
+	masterPort := freePort(t)
+	startProcess(t, "redis-server", "--port", masterPort, "--save", "", "--appendonly", "no", "--dir", dir)
+	waitForRedis(t, "127.0.0.1:"+masterPort)
+
+	// Sentinel rewrites its configuration, so it needs a writable file.
+	sentinelPort := freePort(t)
+	config := filepath.Join(dir, "sentinel.conf")
+	content := "port " + sentinelPort + "\nsentinel monitor mymaster 127.0.0.1 " + masterPort + " 1\n"
+	if err := os.WriteFile(config, []byte(content), 0600); err != nil {
+		t.Fatalf("Failed to write sentinel config: %v", err)
+	}
+	startProcess(t, "redis-sentinel", config)
+	addr := "127.0.0.1:" + sentinelPort
+	waitForRedis(t, addr)
+	return addr
+}
+
+func requireBinaries(t *testing.T, names ...string) {
+	for _, name := range names {
+		if _, err := exec.LookPath(name); err != nil {
+			t.Skipf("%s is not on PATH; see the top of this file for running against existing servers", name)
+		}
+	}
+}
+
+func startProcess(t *testing.T, name string, args ...string) {
+	cmd := exec.Command(name, args...)
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Failed to start %s: %v", name, err)
+	}
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	})
+}
+
+func freePort(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to find a free port: %v", err)
+	}
+	defer listener.Close()
+	return strconv.Itoa(listener.Addr().(*net.TCPAddr).Port)
+}
+
+func waitForRedis(t *testing.T, addr string) {
+	client := redis.NewClient(&redis.Options{Addr: addr})
+	defer client.Close()
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		err := client.Ping(context.Background()).Err()
+		if err == nil {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Redis at %s did not start: %v", addr, err)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
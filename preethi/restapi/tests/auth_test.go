@@ -0,0 +1,224 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Preethi0716/Cache-Library/preethi/restapi/pkg/api"
+	"github.com/Preethi0716/Cache-Library/preethi/restapi/pkg/auth"
+	"github.com/Preethi0716/Cache-Library/preethi/restapi/pkg/cache"
+)
+
+var tokenSecret = bytes.Repeat([]byte{7}, 32)
+
+func newAuthenticator(t *testing.T) *auth.Authenticator {
+	authenticator, err := auth.NewAuthenticator(tokenSecret, map[string]auth.Principal{
+		"ops-key": {Subject: "ops", Grants: []auth.Grant{{Roles: []auth.Role{auth.RoleAdmin}}}},
+		"web-key": {Subject: "web", Grants: []auth.Grant{
+			{Backend: "sessions", Prefix: "user:", Roles: []auth.Role{auth.RoleRead, auth.RoleWrite}},
+			{Backend: "catalog", Roles: []auth.Role{auth.RoleRead}},
+		}},
+		"tenant-key": {Subject: "tenant", Grants: []auth.Grant{{Backend: "sessions", Prefix: "tenant:", Roles: []auth.Role{auth.RoleAdmin}}}},
+		"edge-key":   {Subject: "edge", Grants: []auth.Grant{{Backend: "front", Roles: []auth.Role{auth.RoleAdmin}}}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create authenticator: %v", err)
+	}
+	return authenticator
+}
+
+func newAuthServer(t *testing.T) *httptest.Server {
+	registry := api.NewRegistry()
+	registry.Add("sessions", "lru", cache.NewLRUCache(10))
+	registry.Add("catalog", "lru", cache.NewLRUCache(10))
+	// front reads and writes the keys of sessions, as tiered does those of redis.
+	registry.AddInfo(api.BackendInfo{Name: "front", Type: "tiered", Store: "sessions"}, cache.NewLRUCache(10))
+	unifiedCache := api.NewUnifiedCache(registry)
+	unifiedCache.Auth = newAuthenticator(t)
+	return httptest.NewServer(api.NewRouter(unifiedCache))
+}
+
+func authRequest(t *testing.T, method, url, header, credentials, body string) *http.Response {
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if header != "" {
+		req.Header.Set(header, credentials)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to send %s %s: %v", method, url, err)
+	}
+	return resp
+}
+
+func TestAPI_Authorize(t *testing.T) {
+	server := newAuthServer(t)
+	defer server.Close()
+
+	for _, test := range []struct {
+		name, method, path, header, credentials string
+		status                                  int
+	}{
+		{"probes stay open", "GET", "/healthz", "", "", http.StatusOK},
+		{"no credentials", "GET", "/cache/user:1?cache=sessions", "", "", http.StatusUnauthorized},
+		{"unknown key", "GET", "/cache/user:1?cache=sessions", "X-API-Key", "nope", http.StatusUnauthorized},
+		{"invalid token", "GET", "/cache/user:1?cache=sessions", "Authorization", "Bearer abc.def", http.StatusUnauthorized},
+		{"write in prefix", "POST", "/cache/user:1?cache=sessions", "X-API-Key", "web-key", http.StatusCreated},
+		{"read in prefix", "GET", "/cache/user:1?cache=sessions", "Authorization", "Bearer web-key", http.StatusOK},
+		{"read outside prefix", "GET", "/cache/order:1?cache=sessions", "X-API-Key", "web-key", http.StatusForbidden},
+		{"delete without role", "DELETE", "/cache/user:1?cache=sessions", "X-API-Key", "web-key", http.StatusForbidden},
+		{"write read-only backend", "PUT", "/cache/item?cache=catalog", "X-API-Key", "web-key", http.StatusForbidden},
+		{"list in prefix", "GET", "/cache?backend=sessions&prefix=user:", "X-API-Key", "web-key", http.StatusOK},
+		{"list every key", "GET", "/cache", "X-API-Key", "web-key", http.StatusForbidden},
+		{"stats need admin", "GET", "/stats", "X-API-Key", "web-key", http.StatusForbidden},
+		{"admin", "DELETE", "/cache/user:1?cache=sessions", "X-API-Key", "ops-key", http.StatusNoContent},
+		{"admin stats", "GET", "/stats", "X-API-Key", "ops-key", http.StatusOK},
+		{"sharing backend without its store", "GET", "/cache/user:1?cache=front", "X-API-Key", "edge-key", http.StatusForbidden},
+		{"list sharing backend without its store", "GET", "/cache?backend=front", "X-API-Key", "edge-key", http.StatusForbidden},
+		{"sharing backend with its store", "PUT", "/cache/user:1?cache=front", "X-API-Key", "ops-key", http.StatusCreated},
+	} {
+		body := ""
+		if test.method == "POST" || test.method == "PUT" {
+			body = `{"value":"v"}`
+		}
+		resp := authRequest(t, test.method, server.URL+test.path, test.header, test.credentials, body)
+		resp.Body.Close()
+		if resp.StatusCode != test.status {
+			t.Errorf("%s: expected %v, got %v", test.name, test.status, resp.StatusCode)
+		}
+		if resp.StatusCode == http.StatusUnauthorized && resp.Header.Get("WWW-Authenticate") == "" {
+			t.Errorf("%s: expected a WWW-Authenticate header", test.name)
+		}
+	}
+}
+
+func TestAPI_IssueToken(t *testing.T) {
+	server := newAuthServer(t)
+	defer server.Close()
+
+	request := `{"subject":"batch","grants":[{"backend":"sessions","prefix":"tenant:a:","roles":["read","write"]}],"ttl":"5m"}`
+	resp := authRequest(t, "POST", server.URL+"/auth/tokens", "X-API-Key", "tenant-key", request)
+	defer resp.Body.Close()
+	var issued struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&issued); err != nil || resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected a token, got %v %v", resp.StatusCode, err)
+	}
+	if until := time.Until(issued.ExpiresAt); until <= 4*time.Minute || until > 5*time.Minute {
+		t.Errorf("Expected the token to expire in 5m, got %v", issued.ExpiresAt)
+	}
+
+	// The subject is bound to the issuing key.
+	if principal, err := newAuthenticator(t).Verify(issued.Token); err != nil || principal.Subject != "tenant/batch" || principal.Issuer != "tenant" {
+		t.Errorf("Expected the token of tenant/batch issued by tenant, got %+v %v", principal, err)
+	}
+
+	bearer := "Bearer " + issued.Token
+	for path, status := range map[string]int{
+		"/cache/tenant:a:1?cache=sessions": http.StatusCreated,
+		"/cache/tenant:b:1?cache=sessions": http.StatusForbidden,
+	} {
+		resp := authRequest(t, "POST", server.URL+path, "Authorization", bearer, `{"value":"v"}`)
+		resp.Body.Close()
+		if resp.StatusCode != status {
+			t.Errorf("%s: expected %v, got %v", path, status, resp.StatusCode)
+		}
+	}
+
+	// Tokens only carry what the issuer administers.
+	for credentials, status := range map[string]int{"tenant-key": http.StatusForbidden, "web-key": http.StatusForbidden, "ops-key": http.StatusCreated} {
+		resp := authRequest(t, "POST", server.URL+"/auth/tokens", "X-API-Key", credentials, `{"grants":[{"roles":["read"]}]}`)
+		resp.Body.Close()
+		if resp.StatusCode != status {
+			t.Errorf("%s issuing a global token: expected %v, got %v", credentials, status, resp.StatusCode)
+		}
+	}
+	resp = authRequest(t, "POST", server.URL+"/auth/tokens", "X-API-Key", "edge-key", `{"grants":[{"backend":"front","roles":["read"]}]}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected 403 for a grant on a backend sharing keys the issuer does not administer, got %v", resp.StatusCode)
+	}
+	resp = authRequest(t, "POST", server.URL+"/auth/tokens", "X-API-Key", "ops-key", `{"grants":[{"roles":["read"]}],"ttl":"48h"}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a ttl above the limit, got %v", resp.StatusCode)
+	}
+}
+
+func TestAuthenticator_Tokens(t *testing.T) {
+	authenticator := newAuthenticator(t)
+	token, _, err := authenticator.Issue(auth.Principal{
+		Subject: "batch",
+		Grants:  []auth.Grant{{Backend: "sessions", Roles: []auth.Role{auth.RoleRead}}},
+	}, time.Second)
+	if err != nil {
+		t.Fatalf("Failed to issue token: %v", err)
+	}
+	principal, err := authenticator.Verify(token)
+	if err != nil || principal.Subject != "batch" || !principal.Allows(auth.RoleRead, "sessions", "any") || principal.Allows(auth.RoleWrite, "sessions", "any") {
+		t.Fatalf("Expected the token's read grant, got %+v %v", principal, err)
+	}
+
+	payload, signature, _ := strings.Cut(token, ".")
+	forged, _ := json.Marshal(map[string]interface{}{"sub": "batch", "grants": []auth.Grant{{Roles: []auth.Role{auth.RoleAdmin}}}, "exp": time.Now().Add(time.Hour).Unix()})
+	if _, err := authenticator.Verify(base64.RawURLEncoding.EncodeToString(forged) + "." + signature); !errors.Is(err, auth.ErrInvalidToken) {
+		t.Errorf("Expected a forged payload to be rejected, got %v", err)
+	}
+	other, _ := auth.NewAuthenticator(bytes.Repeat([]byte{8}, 32), nil)
+	if _, err := other.Verify(token); !errors.Is(err, auth.ErrInvalidToken) {
+		t.Errorf("Expected a token signed with another secret to be rejected, got %v", err)
+	}
+	if _, err := authenticator.Verify(payload); !errors.Is(err, auth.ErrInvalidToken) {
+		t.Errorf("Expected an unsigned token to be rejected, got %v", err)
+	}
+
+	time.Sleep(1100 * time.Millisecond)
+	if _, err := authenticator.Verify(token); !errors.Is(err, auth.ErrTokenExpired) {
+		t.Errorf("Expected the token to expire, got %v", err)
+	}
+}
+
+func TestLoadAuthenticator(t *testing.T) {
+	secret := base64.StdEncoding.EncodeToString(tokenSecret)
+	path := filepath.Join(t.TempDir(), "auth.json")
+	content := `{"token_secret": "` + secret + `", "keys": {"web": {"key": "web-key", "grants": [{"backend": "sessions", "prefix": "user:", "roles": ["read"]}]}}}`
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("Failed to write auth file: %v", err)
+	}
+	authenticator, err := auth.LoadAuthenticator(path)
+	if err != nil || !authenticator.CanIssue() {
+		t.Fatalf("Expected an authenticator that issues tokens, got error: %v", err)
+	}
+	req := httptest.NewRequest("GET", "/cache/user:1", nil)
+	req.Header.Set("X-API-Key", "web-key")
+	if principal, err := authenticator.Authenticate(req); err != nil || principal.Subject != "web" {
+		t.Errorf("Expected the web key, got %+v %v", principal, err)
+	}
+
+	for name, content := range map[string]string{
+		"unknown role": `{"keys": {"web": {"key": "k", "grants": [{"roles": ["owner"]}]}}}`,
+		"short secret": `{"token_secret": "c2hvcnQ=", "keys": {}}`,
+		"reused key":   `{"keys": {"a": {"key": "k", "grants": [{"roles": ["read"]}]}, "b": {"key": "k", "grants": [{"roles": ["read"]}]}}}`,
+	} {
+		os.WriteFile(path, []byte(content), 0600)
+		if _, err := auth.LoadAuthenticator(path); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/gob"
+	"testing"
+	"time"
+
+	"github.com/Preethi0716/Cache-Library/preethi/restapi/pkg/cache"
+)
+
+type codecTestValue struct {
+	Name  string
+	Count int
+}
+
+func init() {
+	gob.Register(codecTestValue{})
+}
+
+func TestJSONCodec_RoundTrip(t *testing.T) {
+	codec := cache.JSONCodec{}
+	data, err := codec.Encode(map[string]interface{}{"name": "value1", "count": 2})
+	if err != nil {
+		t.Fatalf("Failed to encode value: %v", err)
+	}
+
+	value, err := codec.Decode(data)
+	if err != nil {
+		t.Fatalf("Failed to decode value: %v", err)
+	}
+	decoded, ok := value.(map[string]interface{})
+	if !ok || decoded["name"] != "value1" || decoded["count"] != float64(2) {
+		t.Fatalf("Expected decoded map, got %#v", value)
+	}
+}
+
+func TestGobCodec_KeepsType(t *testing.T) {
+	codec := cache.GobCodec{}
+	data, err := codec.Encode(codecTestValue{Name: "value1", Count: 2})
+	if err != nil {
+		t.Fatalf("Failed to encode value: %v", err)
+	}
+
+	value, err := codec.Decode(data)
+	if err != nil {
+		t.Fatalf("Failed to decode value: %v", err)
+	}
+	if value != (codecTestValue{Name: "value1", Count: 2}) {
+		t.Fatalf("Expected codecTestValue, got %#v", value)
+	}
+}
+
+func TestBytesCodec_RoundTrip(t *testing.T) {
+	codec := cache.BytesCodec{}
+	data, err := codec.Encode([]byte{0, 1, 2})
+	if err != nil {
+		t.Fatalf("Failed to encode value: %v", err)
+	}
+
+	value, err := codec.Decode(data)
+	if err != nil || !bytes.Equal(value.([]byte), []byte{0, 1, 2}) {
+		t.Fatalf("Expected raw bytes, got %#v", value)
+	}
+
+	if _, err := codec.Encode(42); err == nil {
+		t.Fatal("Expected an error when encoding a number as bytes")
+	}
+}
+
+func TestTypedValue_RoundTrip(t *testing.T) {
+	typed := cache.TypedValue{ContentType: "image/png", Data: []byte{0x89, 'P', 'N', 'G', 0}, ModifiedAt: time.Now()}
+	data, marker, err := cache.EncodeValue(cache.JSONCodec{}, typed)
+	if err != nil || marker != cache.CodecTyped {
+		t.Fatalf("Expected the typed codec, got marker %d (error: %v)", marker, err)
+	}
+
+	value, err := cache.DecodeValue(data, marker)
+	decoded, ok := value.(cache.TypedValue)
+	if err != nil || !ok || decoded.ContentType != "image/png" || !bytes.Equal(decoded.Data, typed.Data) ||
+		!decoded.ModifiedAt.Equal(typed.ModifiedAt) || !decoded.ExpiresAt.IsZero() {
+		t.Fatalf("Expected %#v, got %#v (error: %v)", typed, value, err)
+	}
+
+	if _, err := cache.DecodeValue([]byte{20, 'a'}, cache.CodecTyped); err == nil {
+		t.Fatal("Expected an error for a truncated typed value")
+	}
+}
+
+func TestRegisterCodec_RejectsDuplicateID(t *testing.T) {
+	if err := cache.RegisterCodec(cache.JSONCodec{}); err == nil {
+		t.Fatal("Expected an error for an already registered codec id")
+	}
+}
@@ -0,0 +1,66 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Preethi0716/Cache-Library/preethi/restapi/pkg/cache"
+)
+
+func TestParseCompression(t *testing.T) {
+	for _, name := range []string{"none", "gzip", "zlib", "flate"} {
+		c, err := cache.ParseCompression(name)
+		if err != nil || c.String() != name {
+			t.Fatalf("Expected %v, got %v (error: %v)", name, c, err)
+		}
+	}
+	if _, err := cache.ParseCompression("brotli"); err == nil {
+		t.Fatal("Expected an error for an unknown compression")
+	}
+}
+
+func TestRedisCache_Compression(t *testing.T) {
+	c, err := cache.NewRedisCache("localhost:6379", cache.WithCompression(cache.CompressionGzip, 1024))
+	if err != nil {
+		t.Fatalf("Failed to create Redis cache: %v", err)
+	}
+
+	large := strings.Repeat("value1", 10000)
+	if err := c.Set("compressed", large, time.Minute); err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+	value, err := c.Get("compressed")
+	if err != nil || value != large {
+		t.Fatalf("Expected the large value back, got error: %v", err)
+	}
+
+	stats := c.Stats()["compression_stats"].(map[string]interface{})
+	if stats["compressed_values"].(int64) != 1 || stats["ratio"].(float64) <= 1 {
+		t.Fatalf("Expected one compressed value in stats, got %v", stats)
+	}
+}
+
+func TestMemcachedCache_Compression(t *testing.T) {
+	c, err := cache.NewMemcachedCache("localhost:11211", cache.WithCompression(cache.CompressionZlib, 1024))
+	if err != nil {
+		t.Fatalf("Failed to create Memcached cache: %v", err)
+	}
+
+	large := strings.Repeat("value1", 10000)
+	if err := c.Set("compressed", large, time.Minute); err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+	value, err := c.Get("compressed")
+	if err != nil || value != large {
+		t.Fatalf("Expected the large value back, got error: %v", err)
+	}
+
+	if err := c.Set("small", "value1", time.Minute); err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+	value, err = c.Get("small")
+	if err != nil || value != "value1" {
+		t.Fatalf("Expected value1, got %v", value)
+	}
+}
@@ -0,0 +1,108 @@
+package tests
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Preethi0716/Cache-Library/preethi/restapi/pkg/cache"
+)
+
+func TestAPI_ConditionalGet(t *testing.T) {
+	server, _ := newLRUServer()
+	defer server.Close()
+	url := server.URL + "/cache/key1?cache=inMemory"
+
+	resp, _ := send(t, "POST", url, "application/json", `{"value":"value1","ttl":"1h"}`, nil)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Failed to post: %v", resp.StatusCode)
+	}
+	etag := resp.Header.Get("ETag")
+
+	resp, body := send(t, "GET", url, "", "", nil)
+	if resp.StatusCode != http.StatusOK || body != "value1" || resp.Header.Get("ETag") != etag || etag == "" {
+		t.Fatalf("Expected value1 with ETag %v, got %v %q %v", etag, resp.StatusCode, body, resp.Header.Get("ETag"))
+	}
+	if cacheControl := resp.Header.Get("Cache-Control"); cacheControl != "max-age=3599" && cacheControl != "max-age=3600" {
+		t.Errorf("Expected max-age from the remaining ttl, got %q", cacheControl)
+	}
+
+	resp, _ = send(t, "GET", url, "", "", map[string]string{"If-None-Match": etag})
+	if resp.StatusCode != http.StatusNotModified {
+		t.Errorf("Expected 304 for a matching ETag, got %v", resp.StatusCode)
+	}
+	resp, _ = send(t, "GET", url, "", "", map[string]string{"If-None-Match": `"stale"`})
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected 200 for a stale ETag, got %v", resp.StatusCode)
+	}
+	// A new value gets a new ETag.
+	send(t, "PUT", url, "application/json", `{"value":"value2"}`, nil)
+	resp, _ = send(t, "GET", url, "", "", map[string]string{"If-None-Match": etag})
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected 200 after the value changed, got %v", resp.StatusCode)
+	}
+}
+
+func TestAPI_LastModified(t *testing.T) {
+	server, lru := newLRUServer()
+	defer server.Close()
+	url := server.URL + "/cache/doc?cache=inMemory"
+
+	// Documents are stored with the time they were written; text is stored
+	// as a plain string, which does not know it.
+	send(t, "POST", url, "application/json", `{"value":{"name":"value1"}}`, nil)
+	resp, _ := send(t, "GET", url, "", "", nil)
+	lastModified := resp.Header.Get("Last-Modified")
+	if lastModified == "" {
+		t.Fatal("Expected a Last-Modified header")
+	}
+	resp, _ = send(t, "GET", url, "", "", map[string]string{"If-Modified-Since": lastModified})
+	if resp.StatusCode != http.StatusNotModified {
+		t.Errorf("Expected 304 when not modified since, got %v", resp.StatusCode)
+	}
+	resp, _ = send(t, "GET", url, "", "", map[string]string{"If-Modified-Since": time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat)})
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected 200 when modified since, got %v", resp.StatusCode)
+	}
+
+	send(t, "POST", server.URL+"/cache/text?cache=inMemory", "application/json", `{"value":"value1"}`, nil)
+	if value, err := lru.Get("text"); err != nil || value != "value1" {
+		t.Fatalf("Expected text to be stored as a string, got %#v %v", value, err)
+	}
+	resp, _ = send(t, "GET", server.URL+"/cache/text?cache=inMemory", "", "", nil)
+	if resp.Header.Get("Last-Modified") != "" || resp.Header.Get("ETag") == "" {
+		t.Errorf("Expected an ETag without Last-Modified for text, got %v", resp.Header)
+	}
+}
+
+func TestAPI_Head(t *testing.T) {
+	server, _ := newLRUServer()
+	defer server.Close()
+
+	send(t, "POST", server.URL+"/cache/key1?cache=inMemory", "application/json", `{"value":{"name":"value1"},"ttl":"never"}`, nil)
+
+	resp, body := send(t, "HEAD", server.URL+"/cache/key1?cache=inMemory", "", "", nil)
+	if resp.StatusCode != http.StatusOK || len(body) != 0 || resp.ContentLength != int64(len(`{"name":"value1"}`)) {
+		t.Errorf("Expected headers only, got %v with %d bytes and length %d", resp.StatusCode, len(body), resp.ContentLength)
+	}
+	if resp.Header.Get("Content-Type") != "application/json" || resp.Header.Get("ETag") == "" || resp.Header.Get("Cache-Control") != "" {
+		t.Errorf("Unexpected headers for a value without expiry: %v", resp.Header)
+	}
+
+	resp, _ = send(t, "HEAD", server.URL+"/cache/missing?cache=inMemory", "", "", nil)
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 for a missing key, got %v", resp.StatusCode)
+	}
+}
+
+func TestAPI_ExpiredTypedValue(t *testing.T) {
+	server, lru := newLRUServer()
+	defer server.Close()
+
+	// Backends that round ttls may keep a value a little past its expiry.
+	lru.Set("key1", cache.TypedValue{ContentType: "text/plain", Data: []byte("value1"), ExpiresAt: time.Now().Add(-time.Second)}, time.Minute)
+	resp, _ := send(t, "GET", server.URL+"/cache/key1?cache=inMemory", "", "", nil)
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 for an expired value, got %v", resp.StatusCode)
+	}
+}
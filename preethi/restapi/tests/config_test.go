@@ -0,0 +1,199 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Preethi0716/Cache-Library/preethi/restapi/config"
+	"github.com/Preethi0716/Cache-Library/preethi/restapi/pkg/cache"
+)
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	return path
+}
+
+func TestConfig_Defaults(t *testing.T) {
+	cfg, err := config.Load(nil)
+	if err != nil {
+		t.Fatalf("Failed to load defaults: %v", err)
+	}
+	if cfg.ListenAddr != ":8080" || cfg.RedisAddr != "localhost:6379" || cfg.MaxLRUSize != 5 || cfg.DefaultTTL != time.Minute || cfg.QueryTimeout != 5*time.Second {
+		t.Errorf("Unexpected defaults: %+v", cfg)
+	}
+}
+
+func TestConfig_Precedence(t *testing.T) {
+	path := writeConfigFile(t, "cache.conf", `
+# in-memory cache
+max_lru_size: 50
+default_ttl: 2m
+redis_addr: "redis:6379"
+memcached_servers: mc1:11211=2, mc2:11211
+`)
+	t.Setenv("CACHE_CONFIG_FILE", path)
+	t.Setenv("CACHE_DEFAULT_TTL", "3m")
+	t.Setenv("CACHE_REDIS_ADDR", "redis-env:6379")
+
+	cfg, err := config.Load([]string{"-redis-addr", "redis-flag:6379"})
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.MaxLRUSize != 50 {
+		t.Errorf("Expected max_lru_size from the file, got %d", cfg.MaxLRUSize)
+	}
+	if cfg.DefaultTTL != 3*time.Minute {
+		t.Errorf("Expected default_ttl from the environment, got %v", cfg.DefaultTTL)
+	}
+	if cfg.RedisAddr != "redis-flag:6379" {
+		t.Errorf("Expected redis_addr from the flag, got %v", cfg.RedisAddr)
+	}
+	if len(cfg.MemcachedServers) != 2 || cfg.MemcachedServers[0] != "mc1:11211=2" {
+		t.Errorf("Unexpected memcached servers: %v", cfg.MemcachedServers)
+	}
+}
+
+func TestConfig_JSONFile(t *testing.T) {
+	path := writeConfigFile(t, "cache.json", `{"max_lru_size": 20, "peer_self": "http://a:8080", "peers": ["http://b:8080", "http://c:8080"]}`)
+	cfg, err := config.Load([]string{"-config", path})
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.MaxLRUSize != 20 || len(cfg.Peers) != 2 || cfg.Peers[1] != "http://c:8080" {
+		t.Errorf("Unexpected config from JSON: %+v", cfg)
+	}
+
+	// Large integers are not turned into exponents.
+	path = writeConfigFile(t, "large.json", `{"max_lru_size": 1000000, "rate_limit_daily_quota": 250000000}`)
+	cfg, err = config.Load([]string{"-config", path})
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.MaxLRUSize != 1000000 || cfg.RateLimit.DailyQuota != 250000000 {
+		t.Errorf("Unexpected large integers from JSON: %v %v", cfg.MaxLRUSize, cfg.RateLimit.DailyQuota)
+	}
+}
+
+func TestConfig_InvalidValues(t *testing.T) {
+	if _, err := config.Load([]string{"-max-lru-size", "many"}); err == nil || !strings.Contains(err.Error(), "-max-lru-size") {
+		t.Errorf("Expected an error naming the flag, got %v", err)
+	}
+
+	t.Setenv("CACHE_DEFAULT_TTL", "soon")
+	if _, err := config.Load(nil); err == nil || !strings.Contains(err.Error(), "CACHE_DEFAULT_TTL") {
+		t.Errorf("Expected an error naming the environment variable, got %v", err)
+	}
+	os.Unsetenv("CACHE_DEFAULT_TTL")
+
+	path := writeConfigFile(t, "cache.conf", "max_lru_sise: 10\n")
+	if _, err := config.Load([]string{"-config", path}); err == nil || !strings.Contains(err.Error(), "max_lru_sise") {
+		t.Errorf("Expected an unknown setting error, got %v", err)
+	}
+
+	// Every invalid setting is reported together.
+	_, err := config.Load([]string{"-max-lru-size", "0", "-replication-role", "follower", "-query-timeout", "0s"})
+	if err == nil || !strings.Contains(err.Error(), "max_lru_size") || !strings.Contains(err.Error(), "replication_leader") || !strings.Contains(err.Error(), "query_timeout") {
+		t.Errorf("Expected validation errors for every setting, got %v", err)
+	}
+}
+
+func TestConfig_Backends(t *testing.T) {
+	cfg, err := config.Load([]string{"-backends", "sessions=redis@localhost:6379, catalog=memcached@mc1:11211;mc2:11211=2, scratch=lru"})
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	expected := []config.BackendConfig{
+		{Name: "sessions", Type: "redis", Addr: "localhost:6379"},
+		{Name: "catalog", Type: "memcached", Addr: "mc1:11211;mc2:11211=2"},
+		{Name: "scratch", Type: "lru"},
+	}
+	if len(cfg.Backends) != len(expected) {
+		t.Fatalf("Expected %d backends, got %v", len(expected), cfg.Backends)
+	}
+	for i := range expected {
+		if cfg.Backends[i] != expected[i] {
+			t.Errorf("Expected %+v, got %+v", expected[i], cfg.Backends[i])
+		}
+	}
+
+	if _, err := config.Load([]string{"-backends", "a=lru,a=redis@localhost:6379"}); err == nil || !strings.Contains(err.Error(), "defined twice") {
+		t.Errorf("Expected a duplicate backend error, got %v", err)
+	}
+}
+
+func TestConfig_RedisTopology(t *testing.T) {
+	cfg, err := config.Load([]string{"-redis-topology", "sentinel", "-redis-addrs", "s1:26379, s2:26379", "-redis-master-name", "mymaster"})
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	redisConfig := cfg.Redis()
+	if redisConfig.Topology != cache.RedisSentinel || len(redisConfig.Addrs) != 2 || redisConfig.MasterName != "mymaster" {
+		t.Errorf("Unexpected Redis config: %+v", redisConfig)
+	}
+	if cfg, _ := config.Load(nil); cfg.Redis().Topology != cache.RedisSingle || cfg.Redis().Addrs[0] != cfg.RedisAddr {
+		t.Errorf("Expected a single node at redis_addr by default, got %+v", cfg.Redis())
+	}
+
+	_, err = config.Load([]string{"-redis-topology", "sentinel", "-redis-addrs", "s1"})
+	if err == nil || !strings.Contains(err.Error(), "redis_master_name") || !strings.Contains(err.Error(), "redis_addrs") {
+		t.Errorf("Expected validation errors for the sentinels, got %v", err)
+	}
+	for _, args := range [][]string{
+		{"-redis-topology", "replicas"},
+		{"-redis-topology", "cluster"},
+		{"-redis-addrs", "n1:7000"},
+		{"-redis-master-name", "mymaster"},
+		{"-redis-topology", "cluster", "-redis-addrs", "n1:7000", "-rate-limit-store", "redis"},
+	} {
+		if _, err := config.Load(args); err == nil || !strings.Contains(err.Error(), "redis_") {
+			t.Errorf("Expected a validation error for %v, got %v", args, err)
+		}
+	}
+
+	cfg, err = config.Load([]string{"-backends", "sessions=redis-sentinel@mymaster@s1:26379;s2:26379, shared=redis-cluster@n1:7000;n2:7000"})
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	sessions, err := cfg.Backends[0].Redis()
+	if err != nil || sessions.Topology != cache.RedisSentinel || sessions.MasterName != "mymaster" || len(sessions.Addrs) != 2 {
+		t.Errorf("Unexpected sentinel backend: %+v, %v", sessions, err)
+	}
+	shared, err := cfg.Backends[1].Redis()
+	if err != nil || shared.Topology != cache.RedisCluster || len(shared.Addrs) != 2 {
+		t.Errorf("Unexpected cluster backend: %+v, %v", shared, err)
+	}
+	if _, err := config.Load([]string{"-backends", "sessions=redis-sentinel@s1:26379"}); err == nil || !strings.Contains(err.Error(), "master@") {
+		t.Errorf("Expected an error for a sentinel backend without a master, got %v", err)
+	}
+}
+
+func TestConfig_RateLimit(t *testing.T) {
+	cfg, err := config.Load([]string{"-rate-limit-reads", "100/s", "-rate-limit-writes", "600/m:20", "-rate-limit-store", "redis"})
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.RateLimit.Reads.Burst != 100 || cfg.RateLimit.Writes.Per != time.Minute || cfg.RateLimit.Writes.Burst != 20 || cfg.RateLimitStore != "redis" {
+		t.Errorf("Unexpected rate limits: %+v %v", cfg.RateLimit, cfg.RateLimitStore)
+	}
+	if cfg.LimiterRedisAddr() != cfg.RedisAddr || cfg.RateLimitRedisDB != 1 {
+		t.Errorf("Expected the limits in their own database of redis_addr, got %v %v", cfg.LimiterRedisAddr(), cfg.RateLimitRedisDB)
+	}
+	if cfg, _ := config.Load(nil); cfg.RateLimit.Enabled() || cfg.RateLimitStore != "local" {
+		t.Errorf("Expected no rate limits by default, got %+v", cfg.RateLimit)
+	}
+
+	_, err = config.Load([]string{"-rate-limit-daily-quota", "-1", "-rate-limit-store", "memory", "-rate-limit-redis-db", "0"})
+	if err == nil || !strings.Contains(err.Error(), "rate_limit_daily_quota") || !strings.Contains(err.Error(), "rate_limit_store") || !strings.Contains(err.Error(), "rate_limit_redis_db") {
+		t.Errorf("Expected validation errors for every setting, got %v", err)
+	}
+	// Database 0 of another server holds no cache entries.
+	if _, err := config.Load([]string{"-rate-limit-redis-addr", "limits:6379", "-rate-limit-redis-db", "0"}); err != nil {
+		t.Errorf("Expected a server of their own to hold the limits in database 0, got %v", err)
+	}
+}
@@ -0,0 +1,79 @@
+package tests
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Preethi0716/Cache-Library/preethi/restapi/config"
+	"github.com/Preethi0716/Cache-Library/preethi/restapi/pkg/api"
+	"github.com/Preethi0716/Cache-Library/preethi/restapi/pkg/cache"
+)
+
+// unreachableAddr refuses connections, standing in for a backend that is down.
+const unreachableAddr = "localhost:1"
+
+func TestInitCache_RequiredBackendDown(t *testing.T) {
+	cfg := config.Default()
+	cfg.MemcachedServers = []string{unreachableAddr}
+	if _, err := api.InitCache(cfg); err == nil {
+		t.Fatal("Expected a required backend that is down to fail the start")
+	}
+}
+
+func TestInitCache_OptionalBackendDown(t *testing.T) {
+	cfg := config.Default()
+	cfg.MemcachedServers = []string{unreachableAddr}
+	cfg.OptionalBackends = []string{"memcached"}
+	unifiedCache, err := api.InitCache(cfg)
+	if err != nil {
+		t.Fatalf("Expected an optional backend to start degraded: %v", err)
+	}
+	defer unifiedCache.Close()
+
+	for _, backend := range unifiedCache.Backends.Backends() {
+		expected := "available"
+		if backend.Name == "memcached" {
+			expected = "degraded"
+		}
+		if backend.Status != expected {
+			t.Errorf("Expected %v to be %v, got %v", backend.Name, expected, backend.Status)
+		}
+	}
+
+	memcached, _ := unifiedCache.Backends.Get("memcached")
+	if _, err := memcached.Get("key1"); !errors.Is(err, cache.ErrBackendUnavailable) {
+		t.Errorf("Expected ErrBackendUnavailable, got %v", err)
+	}
+
+	server := httptest.NewServer(api.NewRouter(unifiedCache))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/cache/key1?cache=memcached")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable || resp.Header.Get("Retry-After") == "" {
+		t.Errorf("Expected 503 with Retry-After, got %v %v", resp.StatusCode, resp.Header)
+	}
+
+	// The other backends keep working.
+	resp, err = http.Post(server.URL+"/cache/key1?cache=inMemory", "application/json", strings.NewReader(`{"value":"value1"}`))
+	if err != nil || resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected the in-memory cache to work, got %v %v", resp, err)
+	}
+	resp.Body.Close()
+}
+
+func TestUnavailableCache_RetryAfter(t *testing.T) {
+	c := cache.NewUnavailableCache("redis", errors.New("connection refused"), 5*time.Second)
+	err := c.Set("key1", "value1", time.Minute)
+	var unavailable *cache.UnavailableError
+	if !errors.As(err, &unavailable) || unavailable.RetryAfter != 5*time.Second || unavailable.Backend != "redis" {
+		t.Fatalf("Expected an UnavailableError for redis, got %v", err)
+	}
+}
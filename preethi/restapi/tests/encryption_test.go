@@ -0,0 +1,130 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Preethi0716/Cache-Library/preethi/restapi/pkg/cache"
+)
+
+func newTestKeyRing(t *testing.T, active string, ids ...string) *cache.KeyRing {
+	keys := make(map[string][]byte)
+	for i, id := range ids {
+		keys[id] = bytes.Repeat([]byte{byte(i + 1)}, 32)
+	}
+	ring, err := cache.NewKeyRing(active, keys)
+	if err != nil {
+		t.Fatalf("Failed to create key ring: %v", err)
+	}
+	return ring
+}
+
+func TestEncryptedCache_StoresCiphertext(t *testing.T) {
+	inner := cache.NewLRUCache(2)
+	c := cache.NewEncryptedCache(inner, newTestKeyRing(t, "k1", "k1"))
+
+	if err := c.Set("key1", "value1", time.Minute); err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+
+	stored, err := inner.Get("key1")
+	if err != nil {
+		t.Fatalf("Failed to get stored value: %v", err)
+	}
+	if bytes.Contains(stored.([]byte), []byte("value1")) {
+		t.Fatal("Expected the inner cache to hold ciphertext only")
+	}
+
+	value, err := c.Get("key1")
+	if err != nil || value != "value1" {
+		t.Fatalf("Expected value1, got %v", value)
+	}
+}
+
+func TestEncryptedCache_KeyRotation(t *testing.T) {
+	inner := cache.NewLRUCache(2)
+	old := cache.NewEncryptedCache(inner, newTestKeyRing(t, "k1", "k1"))
+	if err := old.Set("key1", "value1", time.Minute); err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+
+	rotated := cache.NewEncryptedCache(inner, newTestKeyRing(t, "k2", "k1", "k2"))
+	value, err := rotated.Get("key1")
+	if err != nil || value != "value1" {
+		t.Fatalf("Expected value1 after rotation, got %v (error: %v)", value, err)
+	}
+
+	withoutOldKey := cache.NewEncryptedCache(inner, newTestKeyRing(t, "k2", "k2"))
+	if _, err := withoutOldKey.Get("key1"); !errors.Is(err, cache.ErrUnknownKeyID) {
+		t.Fatalf("Expected unknown key id error, got %v", err)
+	}
+}
+
+func TestEncryptedCache_BoundToKey(t *testing.T) {
+	inner := cache.NewLRUCache(2)
+	c := cache.NewEncryptedCache(inner, newTestKeyRing(t, "k1", "k1"))
+	if err := c.Set("key1", "value1", time.Minute); err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+
+	stored, _ := inner.Get("key1")
+	inner.Set("key2", stored, time.Minute)
+	if _, err := c.Get("key2"); err == nil {
+		t.Fatal("Expected an error for a ciphertext copied to another key")
+	}
+}
+
+func TestEncryptedCache_ListingSkipsUndecryptable(t *testing.T) {
+	inner := cache.NewLRUCache(4)
+	c := cache.NewEncryptedCache(inner, newTestKeyRing(t, "k1", "k1"))
+	if err := c.Set("key1", "value1", time.Minute); err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+	inner.Set("plain", "not encrypted", time.Minute)
+	retired := cache.NewEncryptedCache(inner, newTestKeyRing(t, "k0", "k0"))
+	if err := retired.Set("retired", "value2", time.Minute); err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+
+	all, err := c.GetAll()
+	if err != nil {
+		t.Fatalf("Expected undecryptable entries to be skipped, got %v", err)
+	}
+	if len(all) != 1 || all["key1"] != "value1" {
+		t.Fatalf("Expected only key1 in GetAll, got %v", all)
+	}
+
+	entries, _, err := c.Scan("", "*", 10)
+	if err != nil {
+		t.Fatalf("Expected undecryptable entries to be skipped, got %v", err)
+	}
+	if len(entries) != 1 || entries["key1"] != "value1" {
+		t.Fatalf("Expected only key1 in Scan, got %v", entries)
+	}
+}
+
+func TestLoadKeyRing(t *testing.T) {
+	key := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32))
+	path := filepath.Join(t.TempDir(), "keys.json")
+	content := `{"active": "k1", "keys": {"k1": "` + key + `"}}`
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("Failed to write key file: %v", err)
+	}
+
+	ring, err := cache.LoadKeyRing(path)
+	if err != nil || ring.ActiveKeyID() != "k1" {
+		t.Fatalf("Expected key ring with active key k1, got error: %v", err)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"active": "k2", "keys": {"k1": "`+key+`"}}`), 0600); err != nil {
+		t.Fatalf("Failed to write key file: %v", err)
+	}
+	if _, err := cache.LoadKeyRing(path); err == nil {
+		t.Fatal("Expected an error for a missing active key")
+	}
+}
@@ -0,0 +1,73 @@
+package tests
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/Preethi0716/Cache-Library/preethi/restapi/pkg/cache"
+)
+
+func TestHashRing_AddingNodeMovesFewKeys(t *testing.T) {
+	before := cache.NewHashRing(map[string]int{"node1": 1, "node2": 1, "node3": 1})
+	after := cache.NewHashRing(map[string]int{"node1": 1, "node2": 1, "node3": 1, "node4": 1})
+
+	const keys = 10000
+	moved := 0
+	for i := 0; i < keys; i++ {
+		key := fmt.Sprintf("key%d", i)
+		owner1, _ := before.Get(key)
+		owner2, _ := after.Get(key)
+		if owner1 != owner2 {
+			if owner2 != "node4" {
+				t.Fatalf("Expected %v to move to the new node, moved to %v", key, owner2)
+			}
+			moved++
+		}
+	}
+	// About a quarter of the keys should move to the new node.
+	if moved < keys/8 || moved > keys/2 {
+		t.Fatalf("Expected about %d moved keys, got %d", keys/4, moved)
+	}
+}
+
+func TestHashRing_Weights(t *testing.T) {
+	ring := cache.NewHashRing(map[string]int{"small": 1, "large": 3})
+
+	counts := map[string]int{}
+	for i := 0; i < 10000; i++ {
+		owner, _ := ring.Get(fmt.Sprintf("key%d", i))
+		counts[owner]++
+	}
+	if counts["large"] < 2*counts["small"] {
+		t.Fatalf("Expected the heavier node to own most keys, got %v", counts)
+	}
+}
+
+func TestHashRing_WalkVisitsEveryNode(t *testing.T) {
+	ring := cache.NewHashRing(map[string]int{"node1": 1, "node2": 1, "node3": 1})
+
+	seen := map[string]bool{}
+	ring.Walk("key1", func(node string) bool {
+		seen[node] = true
+		return true
+	})
+	if len(seen) != 3 {
+		t.Fatalf("Expected to walk 3 nodes, got %v", seen)
+	}
+}
+
+func TestParseMemcachedServers(t *testing.T) {
+	servers, err := cache.ParseMemcachedServers([]string{"localhost:11211", " localhost:11212=3 "})
+	if err != nil {
+		t.Fatalf("Failed to parse servers: %v", err)
+	}
+	if len(servers) != 2 || servers[1].Addr != "localhost:11212" || servers[1].Weight != 3 {
+		t.Fatalf("Unexpected servers %v", servers)
+	}
+
+	for _, invalid := range [][]string{{"localhost"}, {"localhost:11211=0"}, {}} {
+		if _, err := cache.ParseMemcachedServers(invalid); err == nil {
+			t.Fatalf("Expected an error for %v", invalid)
+		}
+	}
+}
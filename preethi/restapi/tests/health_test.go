@@ -0,0 +1,76 @@
+package tests
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Preethi0716/Cache-Library/preethi/restapi/pkg/api"
+	"github.com/Preethi0716/Cache-Library/preethi/restapi/pkg/cache"
+)
+
+type readyResponse struct {
+	Status   string `json:"status"`
+	Backends map[string]struct {
+		Status  string `json:"status"`
+		Latency string `json:"latency"`
+		Error   string `json:"error"`
+	} `json:"backends"`
+}
+
+func getReady(t *testing.T, unifiedCache *api.UnifiedCache) (int, readyResponse) {
+	recorder := httptest.NewRecorder()
+	api.HandleReadyRequest(unifiedCache)(recorder, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	var body readyResponse
+	if err := json.NewDecoder(recorder.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to decode readiness: %v", err)
+	}
+	return recorder.Code, body
+}
+
+func TestHealth_Liveness(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	api.HandleHealthRequest()(recorder, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %v", recorder.Code)
+	}
+}
+
+func TestHealth_Readiness(t *testing.T) {
+	registry := api.NewRegistry()
+	registry.Add("inMemory", "lru", cache.NewLRUCache(10))
+	code, body := getReady(t, api.NewUnifiedCache(registry))
+	if code != http.StatusOK || body.Status != "ready" || body.Backends["inMemory"].Status != "up" || body.Backends["inMemory"].Latency == "" {
+		t.Fatalf("Expected a ready instance, got %v %+v", code, body)
+	}
+
+	registry = api.NewRegistry()
+	registry.Add("inMemory", "lru", cache.NewLRUCache(10))
+	registry.Add("memcached", "memcached", cache.NewUnavailableCache("memcached", errors.New("connection refused"), time.Second))
+	code, body = getReady(t, api.NewUnifiedCache(registry))
+	if code != http.StatusServiceUnavailable || body.Backends["memcached"].Status != "down" || body.Backends["memcached"].Error == "" {
+		t.Fatalf("Expected a required backend that is down to fail readiness, got %v %+v", code, body)
+	}
+}
+
+func TestHealth_ReadinessAgainstServers(t *testing.T) {
+	redisCache, err := cache.NewRedisCache("localhost:6379")
+	if err != nil {
+		t.Fatalf("Failed to connect to Redis: %v", err)
+	}
+	memcachedCache, err := cache.NewMemcachedCache("localhost:11211")
+	if err != nil {
+		t.Fatalf("Failed to connect to memcached: %v", err)
+	}
+	registry := api.NewRegistry()
+	registry.Add("redis", "redis", cache.NewResilientCache("redis", redisCache, cache.DefaultResiliencePolicy()))
+	registry.Add("memcached", "memcached", memcachedCache)
+
+	code, body := getReady(t, api.NewUnifiedCache(registry))
+	if code != http.StatusOK || body.Backends["redis"].Status != "up" || body.Backends["memcached"].Status != "up" {
+		t.Fatalf("Expected Redis and memcached to be up, got %v %+v", code, body)
+	}
+}
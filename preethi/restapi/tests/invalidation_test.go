@@ -0,0 +1,130 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Preethi0716/Cache-Library/preethi/restapi/config"
+	"github.com/Preethi0716/Cache-Library/preethi/restapi/pkg/api"
+	"github.com/Preethi0716/Cache-Library/preethi/restapi/pkg/cache"
+)
+
+func newTestBus(t *testing.T, nodeID string, local *cache.LRUCache) *cache.InvalidationBus {
+	redisCache, err := cache.NewRedisCache("localhost:6379")
+	if err != nil {
+		t.Fatalf("Failed to create Redis cache: %v", err)
+	}
+	bus := cache.NewInvalidationBus(redisCache, "test:invalidations", nodeID, local)
+	if err := bus.Start(); err != nil {
+		t.Fatalf("Failed to start invalidation bus: %v", err)
+	}
+	t.Cleanup(func() { bus.Close() })
+	return bus
+}
+
+func waitFor(t *testing.T, condition func() bool) {
+	deadline := time.Now().Add(2 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for condition")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestInvalidationBus_DropsRemoteCopies(t *testing.T) {
+	localA, localB := cache.NewLRUCache(10), cache.NewLRUCache(10)
+	nodeA := cache.NewInvalidatingCache(localA, newTestBus(t, "nodeA", localA))
+	newTestBus(t, "nodeB", localB)
+
+	localB.Set("key1", "stale", time.Minute)
+	if err := nodeA.Set("key1", "value1", time.Minute); err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+
+	waitFor(t, func() bool {
+		_, err := localB.Get("key1")
+		return err != nil
+	})
+	// The node that published the invalidation keeps its own value.
+	if value, err := localA.Get("key1"); err != nil || value != "value1" {
+		t.Fatalf("Expected value1 on the publishing node, got %v", value)
+	}
+}
+
+func TestInvalidationBus_Namespace(t *testing.T) {
+	localA, localB := cache.NewLRUCache(10), cache.NewLRUCache(10)
+	nodeA := cache.NewInvalidatingCache(localA, newTestBus(t, "nodeA", localA))
+	newTestBus(t, "nodeB", localB)
+
+	localB.Set("user:1", "value1", time.Minute)
+	localB.Set("user:2", "value2", time.Minute)
+	localB.Set("order:1", "value3", time.Minute)
+	if err := nodeA.DeleteNamespace("user:"); err != nil {
+		t.Fatalf("Failed to delete namespace: %v", err)
+	}
+
+	waitFor(t, func() bool {
+		_, err1 := localB.Get("user:1")
+		_, err2 := localB.Get("user:2")
+		return err1 != nil && err2 != nil
+	})
+	if _, err := localB.Get("order:1"); err != nil {
+		t.Fatal("Expected keys outside the namespace to stay")
+	}
+}
+
+func TestInitCache_InvalidatesEachLocalCacheApart(t *testing.T) {
+	nodes := make([]*api.UnifiedCache, 2)
+	for i := range nodes {
+		unifiedCache, err := api.InitCache(config.Default())
+		if err != nil {
+			t.Fatalf("Failed to initialize caches: %v", err)
+		}
+		defer unifiedCache.Close()
+		nodes[i] = unifiedCache
+	}
+	tieredA, _ := nodes[0].Backends.Get("tiered")
+	tieredB, _ := nodes[1].Backends.Get("tiered")
+	inMemoryB, _ := nodes[1].Backends.Get("inMemory")
+
+	if err := tieredA.Set("apart:key1", "value1", time.Minute); err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+	// Node B keeps a local copy of the tiered entry, and an entry of its
+	// own under the same key in the in-memory cache.
+	if value, err := tieredB.Get("apart:key1"); err != nil || value != "value1" {
+		t.Fatalf("Expected value1 through tiered, got %v (error: %v)", value, err)
+	}
+	inMemoryB.Set("apart:key1", "other", time.Minute)
+
+	if err := tieredA.Set("apart:key1", "value2", time.Minute); err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+	waitFor(t, func() bool {
+		value, _ := tieredB.Get("apart:key1")
+		return value == "value2"
+	})
+	if value, err := inMemoryB.Get("apart:key1"); err != nil || value != "other" {
+		t.Fatalf("Expected the in-memory entry to stay, got %v (error: %v)", value, err)
+	}
+}
+
+func TestLRUCache_DeletePrefixAndFlush(t *testing.T) {
+	c := cache.NewLRUCache(10)
+	c.Set("user:1", "value1", time.Minute)
+	c.Set("user:2", "value2", time.Minute)
+	c.Set("order:1", "value3", time.Minute)
+
+	if deleted := c.DeletePrefix("user:"); deleted != 2 {
+		t.Fatalf("Expected 2 deleted keys, got %d", deleted)
+	}
+	if _, err := c.Get("order:1"); err != nil {
+		t.Fatal("Expected order:1 to stay")
+	}
+
+	c.Flush()
+	if all, _ := c.GetAll(); len(all) != 0 {
+		t.Fatalf("Expected an empty cache after flush, got %v", all)
+	}
+}
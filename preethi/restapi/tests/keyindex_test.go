@@ -0,0 +1,26 @@
+package tests
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Preethi0716/Cache-Library/preethi/restapi/pkg/cache"
+)
+
+func TestLocalKeyIndex(t *testing.T) {
+	index := cache.NewLocalKeyIndex()
+	index.Add("key2")
+	index.Add("key1")
+	index.Add("key2")
+
+	keys, err := index.Keys()
+	if err != nil || !reflect.DeepEqual(keys, []string{"key1", "key2"}) {
+		t.Fatalf("Expected [key1 key2], got %v", keys)
+	}
+
+	index.Remove("key1", "missing")
+	keys, _ = index.Keys()
+	if !reflect.DeepEqual(keys, []string{"key2"}) {
+		t.Fatalf("Expected [key2], got %v", keys)
+	}
+}
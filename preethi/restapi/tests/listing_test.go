@@ -0,0 +1,230 @@
+package tests
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/Preethi0716/Cache-Library/preethi/restapi/pkg/api"
+	"github.com/Preethi0716/Cache-Library/preethi/restapi/pkg/cache"
+)
+
+type listing struct {
+	Backends []struct {
+		Backend string `json:"backend"`
+		Entries []struct {
+			Key   string      `json:"key"`
+			Value interface{} `json:"value"`
+			TTL   string      `json:"ttl"`
+		} `json:"entries"`
+		Status string `json:"status"`
+		Error  string `json:"error"`
+	} `json:"backends"`
+	Cursor string `json:"cursor"`
+}
+
+// slowCache answers after delay, like a backend that hangs.
+type slowCache struct {
+	cache.Cache
+	delay time.Duration
+}
+
+func (c slowCache) GetAll() (map[string]interface{}, error) {
+	time.Sleep(c.delay)
+	return c.Cache.GetAll()
+}
+
+func newUnifiedCache(backends map[string]cache.Cache) *api.UnifiedCache {
+	registry := api.NewRegistry()
+	for name, c := range backends {
+		registry.Add(name, "lru", c)
+	}
+	return api.NewUnifiedCache(registry)
+}
+
+func newListingServer(backends map[string]cache.Cache) *httptest.Server {
+	return newListingServerFor(newUnifiedCache(backends))
+}
+
+func newListingServerFor(unifiedCache *api.UnifiedCache) *httptest.Server {
+	return httptest.NewServer(api.NewRouter(unifiedCache))
+}
+
+func list(t *testing.T, server *httptest.Server, query url.Values) (int, listing) {
+	resp, err := http.Get(server.URL + "/cache?" + query.Encode())
+	if err != nil {
+		t.Fatalf("Failed to list: %v", err)
+	}
+	defer resp.Body.Close()
+	var result listing
+	if resp.StatusCode == http.StatusOK {
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			t.Fatalf("Failed to decode listing: %v", err)
+		}
+	}
+	return resp.StatusCode, result
+}
+
+// listAll follows the cursor and returns backend/key pairs in order.
+func listAll(t *testing.T, server *httptest.Server, query url.Values) []string {
+	var listed []string
+	for pages := 0; pages < 100; pages++ {
+		status, page := list(t, server, query)
+		if status != http.StatusOK {
+			t.Fatalf("Expected 200, got %v", status)
+		}
+		for _, group := range page.Backends {
+			for _, entry := range group.Entries {
+				listed = append(listed, group.Backend+"/"+entry.Key)
+			}
+		}
+		if page.Cursor == "" {
+			return listed
+		}
+		query.Set("cursor", page.Cursor)
+	}
+	t.Fatal("Listing did not finish")
+	return nil
+}
+
+func TestAPI_ListGroupedByBackend(t *testing.T) {
+	sessions, catalog := cache.NewLRUCache(100), cache.NewLRUCache(100)
+	sessions.Set("shared", "from sessions", time.Minute)
+	catalog.Set("shared", "from catalog", time.Minute)
+	catalog.Set("typed", cache.TypedValue{ContentType: "text/plain", Data: []byte("value1")}, 0)
+	server := newListingServer(map[string]cache.Cache{"sessions": sessions, "catalog": catalog})
+	defer server.Close()
+
+	status, page := list(t, server, url.Values{})
+	if status != http.StatusOK || len(page.Backends) != 2 || page.Cursor != "" {
+		t.Fatalf("Expected both backends on one page, got %v %+v", status, page)
+	}
+	if page.Backends[0].Backend != "catalog" || page.Backends[0].Entries[0].Value != "from catalog" || page.Backends[1].Entries[0].Value != "from sessions" {
+		t.Errorf("Expected identical keys to be listed under each backend, got %+v", page)
+	}
+	if typed := page.Backends[0].Entries[1]; typed.Key != "typed" || typed.TTL != "never" || typed.Value != "value1" {
+		t.Errorf("Expected the typed value with its ttl, got %+v", typed)
+	}
+
+	if shared := page.Backends[1].Entries[0]; shared.TTL != "1m0s" {
+		t.Errorf("Expected the ttl kept by the backend, got %+v", shared)
+	}
+
+	if _, page := list(t, server, url.Values{"backend": {"sessions"}}); len(page.Backends) != 1 || page.Backends[0].Backend != "sessions" {
+		t.Errorf("Expected only the sessions backend, got %+v", page)
+	}
+	for name, query := range map[string]url.Values{
+		"unknown backend": {"backend": {"missing"}},
+		"bad limit":       {"limit": {"0"}},
+		"bad cursor":      {"cursor": {"not a cursor"}},
+	} {
+		if status, _ := list(t, server, query); status != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %v", name, status)
+		}
+	}
+}
+
+func TestAPI_ListPages(t *testing.T) {
+	first, second := cache.NewLRUCache(100), cache.NewLRUCache(100)
+	for i := 0; i < 5; i++ {
+		first.Set(fmt.Sprintf("user:%d", i), "value", time.Minute)
+		second.Set(fmt.Sprintf("user:%d", i), "value", time.Minute)
+		second.Set(fmt.Sprintf("order:%d", i), "value", time.Minute)
+	}
+	first.Set("user*odd", "value", time.Minute)
+	server := newListingServer(map[string]cache.Cache{"first": first, "second": second})
+	defer server.Close()
+
+	listed := listAll(t, server, url.Values{"prefix": {"user:"}, "limit": {"3"}})
+	if len(listed) != 10 || listed[0] != "first/user:0" || listed[9] != "second/user:4" {
+		t.Errorf("Expected 10 user keys over both backends, got %v", listed)
+	}
+	if listed := listAll(t, server, url.Values{"prefix": {"user*"}}); len(listed) != 1 {
+		t.Errorf("Expected the prefix to be matched literally, got %v", listed)
+	}
+}
+
+func TestAPI_ListBackendFailure(t *testing.T) {
+	lru := cache.NewLRUCache(10)
+	lru.Set("key1", "value1", time.Minute)
+	server := newListingServer(map[string]cache.Cache{
+		"inMemory": lru,
+		"redis":    cache.NewUnavailableCache("redis", errors.New("connection refused"), time.Second),
+	})
+	defer server.Close()
+
+	_, page := list(t, server, url.Values{})
+	if len(page.Backends) != 2 || len(page.Backends[0].Entries) != 1 || page.Backends[1].Error == "" {
+		t.Errorf("Expected the in-memory entries and an error for redis, got %+v", page)
+	}
+	if page.Backends[0].Status != "ok" || page.Backends[1].Status != "unavailable" {
+		t.Errorf("Expected ok and unavailable statuses, got %+v", page)
+	}
+}
+
+func TestAPI_ListSlowBackend(t *testing.T) {
+	fast, slow := cache.NewLRUCache(10), cache.NewLRUCache(10)
+	for i := 0; i < 4; i++ {
+		fast.Set(fmt.Sprintf("key%d", i), "value", time.Minute)
+		slow.Set(fmt.Sprintf("key%d", i), "value", time.Minute)
+	}
+	unifiedCache := newUnifiedCache(map[string]cache.Cache{
+		"fast": fast,
+		"slow": slowCache{Cache: slow, delay: time.Second},
+	})
+	unifiedCache.SetQueryTimeout(100 * time.Millisecond)
+	server := newListingServerFor(unifiedCache)
+	defer server.Close()
+
+	start := time.Now()
+	status, page := list(t, server, url.Values{"limit": {"2"}})
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Expected the listing not to wait for the slow backend, took %v", elapsed)
+	}
+	if status != http.StatusOK || len(page.Backends) != 2 {
+		t.Fatalf("Expected both backends, got %v %+v", status, page)
+	}
+	if page.Backends[0].Status != "ok" || len(page.Backends[0].Entries) != 2 {
+		t.Errorf("Expected the fast backend's first page, got %+v", page.Backends[0])
+	}
+	if page.Backends[1].Status != "timeout" || len(page.Backends[1].Entries) != 0 {
+		t.Errorf("Expected the slow backend to time out, got %+v", page.Backends[1])
+	}
+
+	// The cursor keeps the slow backend where it was, so it is not skipped.
+	_, page = list(t, server, url.Values{"limit": {"2"}, "cursor": {page.Cursor}})
+	if len(page.Backends) != 2 || len(page.Backends[0].Entries) != 2 || page.Backends[1].Status != "timeout" || page.Cursor == "" {
+		t.Fatalf("Expected the rest of the fast backend and the slow backend again, got %+v", page)
+	}
+	unifiedCache.SetQueryTimeout(2 * time.Second)
+	_, page = list(t, server, url.Values{"limit": {"2"}, "cursor": {page.Cursor}})
+	if len(page.Backends) != 1 || page.Backends[0].Backend != "slow" || len(page.Backends[0].Entries) != 2 || page.Backends[0].Entries[0].Key != "key0" {
+		t.Errorf("Expected the first page of the slow backend, got %+v", page)
+	}
+}
+
+func TestRedisCache_ListPages(t *testing.T) {
+	c, err := cache.NewRedisCache("localhost:6379")
+	if err != nil {
+		t.Fatalf("Failed to create Redis cache: %v", err)
+	}
+	c.DeleteMatching("listing:*")
+	for i := 0; i < 7; i++ {
+		c.Set(fmt.Sprintf("listing:%d", i), "value", time.Minute)
+	}
+	server := newListingServer(map[string]cache.Cache{"redis": c})
+	defer server.Close()
+
+	if listed := listAll(t, server, url.Values{"prefix": {"listing:"}, "limit": {"2"}}); len(listed) != 7 {
+		t.Errorf("Expected 7 keys, got %v", listed)
+	}
+	_, page := list(t, server, url.Values{"prefix": {"listing:0"}})
+	if entries := page.Backends[0].Entries; len(entries) != 1 || entries[0].TTL != "1m0s" {
+		t.Errorf("Expected the ttl of the Redis key, got %+v", entries)
+	}
+}
@@ -1,6 +1,7 @@
 package tests
 
 import (
+	"fmt"
 	"testing"
 	"time"
 
@@ -74,3 +75,132 @@ func TestMemcachedCache_TTL_Expiration(t *testing.T) {
 		t.Fatalf("Expected empty value, got: %v", value)
 	}
 }
+
+func TestMemcachedCache_TypedValues(t *testing.T) {
+	c, err := cache.NewMemcachedCache("localhost:11211", cache.WithCodec(cache.GobCodec{}))
+	if err != nil {
+		t.Fatalf("Failed to create Memcached cache: %v", err)
+	}
+
+	values := map[string]interface{}{
+		"typed:string": "value1",
+		"typed:bytes":  []byte{0, 1, 2},
+		"typed:int":    42,
+		"typed:struct": codecTestValue{Name: "value1", Count: 2},
+	}
+	for key, want := range values {
+		if err := c.Set(key, want, time.Minute); err != nil {
+			t.Fatalf("Failed to set %v: %v", key, err)
+		}
+		got, err := c.Get(key)
+		if err != nil {
+			t.Fatalf("Failed to get %v: %v", key, err)
+		}
+		if fmt.Sprintf("%T %v", got, got) != fmt.Sprintf("%T %v", want, want) {
+			t.Fatalf("Expected %T %v for %v, got %T %v", want, want, key, got, got)
+		}
+	}
+}
+
+func TestMemcachedCache_KeyIndex(t *testing.T) {
+	index := cache.NewLocalKeyIndex()
+	c, err := cache.NewMemcachedCache("localhost:11211", cache.WithKeyIndex(index))
+	if err != nil {
+		t.Fatalf("Failed to create Memcached cache: %v", err)
+	}
+
+	for i := 0; i < 5; i++ {
+		if err := c.Set(fmt.Sprintf("indexed:%d", i), fmt.Sprintf("value%d", i), time.Minute); err != nil {
+			t.Fatalf("Failed to set value: %v", err)
+		}
+	}
+	c.Delete("indexed:0")
+
+	all, err := c.GetAll()
+	if err != nil {
+		t.Fatalf("Failed to get all entries: %v", err)
+	}
+	if len(all) != 4 || all["indexed:1"] != "value1" {
+		t.Fatalf("Expected 4 indexed entries, got %v", all)
+	}
+
+	entries, next, err := c.Scan("", "indexed:*", 3)
+	if err != nil || len(entries) != 3 || next == "" {
+		t.Fatalf("Expected a first page of 3 entries, got %v (next %q, error: %v)", entries, next, err)
+	}
+	entries, next, err = c.Scan(next, "indexed:*", 3)
+	if err != nil || len(entries) != 1 || next != "" {
+		t.Fatalf("Expected a last page of 1 entry, got %v (next %q, error: %v)", entries, next, err)
+	}
+
+	// Keys evicted by memcached are dropped from the index on the next listing.
+	index.Add("indexed:evicted")
+	c.GetAll()
+	keys, _ := index.Keys()
+	for _, key := range keys {
+		if key == "indexed:evicted" {
+			t.Fatal("Expected the evicted key to be removed from the index")
+		}
+	}
+}
+
+func TestMemcachedCache_SharedKeyIndex(t *testing.T) {
+	first, err := cache.NewMemcachedCache("localhost:11211", cache.WithSharedKeyIndex("test:key-index"))
+	if err != nil {
+		t.Fatalf("Failed to create Memcached cache: %v", err)
+	}
+	second, err := cache.NewMemcachedCache("localhost:11211", cache.WithSharedKeyIndex("test:key-index"))
+	if err != nil {
+		t.Fatalf("Failed to create Memcached cache: %v", err)
+	}
+
+	first.Set("shared:1", "value1", time.Minute)
+	second.Set("shared:2", "value2", time.Minute)
+
+	entries, _, err := first.Scan("", "shared:*", 10)
+	if err != nil || len(entries) != 2 || entries["shared:2"] != "value2" {
+		t.Fatalf("Expected both shared entries, got %v (error: %v)", entries, err)
+	}
+}
+
+func TestMemcachedCache_FailedServerIsSkipped(t *testing.T) {
+	// Nothing listens on localhost:1, so that server fails the startup ping
+	// and the two pings after it, is marked down and its keys are rehashed
+	// to the live server.
+	c, err := cache.NewMemcachedCacheWithServers([]cache.MemcachedServer{
+		{Addr: "localhost:11211", Weight: 1},
+		{Addr: "localhost:1", Weight: 1},
+	}, cache.WithServerFailureThreshold(3), cache.WithServerDownPeriod(time.Minute))
+	if err != nil {
+		t.Fatalf("Failed to create Memcached cache: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if servers := c.Stats()["servers"].(map[string]string); servers["localhost:1"] != "up" {
+			t.Fatalf("Expected a server to stay up until its third failure, got %v", servers)
+		}
+		if err := c.Ping(); err != nil {
+			t.Fatalf("Expected the live server to answer, got %v", err)
+		}
+	}
+
+	for i := 0; i < 20; i++ {
+		key := fmt.Sprintf("ring:%d", i)
+		if err := c.Set(key, "value", time.Minute); err != nil {
+			t.Fatalf("Failed to set %v: %v", key, err)
+		}
+		if value, err := c.Get(key); err != nil || value != "value" {
+			t.Fatalf("Expected value for %v, got %v (error: %v)", key, value, err)
+		}
+	}
+
+	servers := c.Stats()["servers"].(map[string]string)
+	if servers["localhost:1"] != "down" || servers["localhost:11211"] != "up" {
+		t.Fatalf("Unexpected server status %v", servers)
+	}
+	// One live server keeps the cache ready.
+	for i := 0; i < 3; i++ {
+		if err := c.Ping(); err != nil {
+			t.Fatalf("Expected the live server to answer, got %v", err)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package tests
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Preethi0716/Cache-Library/preethi/restapi/pkg/cache"
+	"github.com/Preethi0716/Cache-Library/preethi/restapi/pkg/cluster"
+	"github.com/gorilla/mux"
+)
+
+// startPeers runs one PeerCache per test server, all knowing each other.
+func startPeers(t *testing.T, count int) ([]*cluster.PeerCache, []*httptest.Server) {
+	routers := make([]*mux.Router, count)
+	servers := make([]*httptest.Server, count)
+	urls := make([]string, count)
+	for i := range servers {
+		router := mux.NewRouter()
+		routers[i] = router
+		servers[i] = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			router.ServeHTTP(w, r)
+		}))
+		urls[i] = servers[i].URL
+		t.Cleanup(servers[i].Close)
+	}
+	peers := make([]*cluster.PeerCache, count)
+	for i := range peers {
+		peers[i] = cluster.NewPeerCache(urls[i], urls, 100, 10, time.Minute)
+		peers[i].RegisterRoutes(routers[i])
+	}
+	return peers, servers
+}
+
+func TestPeerCache_SharedAcrossPeers(t *testing.T) {
+	peers, _ := startPeers(t, 3)
+
+	for i := 0; i < 30; i++ {
+		key := fmt.Sprintf("key%d", i)
+		if err := peers[i%3].Set(key, fmt.Sprintf("value%d", i), time.Minute); err != nil {
+			t.Fatalf("Failed to set %v: %v", key, err)
+		}
+	}
+
+	// Every peer sees every key, whichever peer wrote it.
+	for _, peer := range peers {
+		for i := 0; i < 30; i++ {
+			key := fmt.Sprintf("key%d", i)
+			value, err := peer.Get(key)
+			if err != nil || value != fmt.Sprintf("value%d", i) {
+				t.Fatalf("Expected value%d for %v, got %v (error: %v)", i, key, value, err)
+			}
+		}
+	}
+
+	// Each key is stored once, on its owner.
+	owned := 0
+	for _, peer := range peers {
+		owned += peer.Stats()["local"].(map[string]interface{})["entries"].(int)
+	}
+	if owned != 30 {
+		t.Fatalf("Expected 30 owned entries over all peers, got %d", owned)
+	}
+
+	all, err := peers[0].GetAll()
+	if err != nil || len(all) != 30 {
+		t.Fatalf("Expected 30 entries in GetAll, got %d (error: %v)", len(all), err)
+	}
+
+	if err := peers[1].Delete("key4"); err != nil {
+		t.Fatalf("Failed to delete key: %v", err)
+	}
+	if _, err := peers[2].Get("key4"); err == nil {
+		t.Fatal("Expected an error for a deleted key")
+	}
+}
+
+func TestPeerCache_AddAndUpdate(t *testing.T) {
+	peers, _ := startPeers(t, 3)
+
+	for i := 0; i < 10; i++ {
+		key := fmt.Sprintf("counter%d", i)
+		if err := cache.Add(peers[i%3], key, 0, time.Minute); err != nil {
+			t.Fatalf("Failed to add %v: %v", key, err)
+		}
+		if err := cache.Add(peers[(i+1)%3], key, 0, time.Minute); !errors.Is(err, cache.ErrKeyExists) {
+			t.Fatalf("Expected %v to exist on every peer, got %v", key, err)
+		}
+	}
+
+	// Increments from every peer at once are all counted by the owners.
+	var wg sync.WaitGroup
+	for _, peer := range peers {
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func(peer *cluster.PeerCache, key string) {
+				defer wg.Done()
+				err := cache.Update(peer, key, func(current interface{}) (interface{}, time.Duration, error) {
+					return current.(int) + 1, time.Minute, nil
+				})
+				if err != nil {
+					t.Errorf("Failed to update %v: %v", key, err)
+				}
+			}(peer, fmt.Sprintf("counter%d", i))
+		}
+	}
+	wg.Wait()
+	for i := 0; i < 10; i++ {
+		key := fmt.Sprintf("counter%d", i)
+		if value, err := peers[0].Get(key); err != nil || value != 3 {
+			t.Errorf("Expected %v to be 3, got %v (error: %v)", key, value, err)
+		}
+	}
+	if err := cache.Update(peers[0], "missing", func(current interface{}) (interface{}, time.Duration, error) {
+		return current, 0, nil
+	}); !cache.IsCacheMiss(err) {
+		t.Errorf("Expected a cache miss updating a missing key, got %v", err)
+	}
+}
+
+func TestPeerCache_Scan(t *testing.T) {
+	peers, _ := startPeers(t, 3)
+	for i := 0; i < 30; i++ {
+		peers[0].Set(fmt.Sprintf("key%d", i), i, time.Minute)
+	}
+	peers[0].Set("other", "value", time.Minute)
+
+	seen := make(map[string]bool)
+	cursor := ""
+	for pages := 0; pages < 100; pages++ {
+		entries, next, err := peers[1].Scan(cursor, "key*", 4)
+		if err != nil {
+			t.Fatalf("Failed to scan: %v", err)
+		}
+		if len(entries) > 4 {
+			t.Fatalf("Expected at most 4 entries per page, got %v", entries)
+		}
+		for key := range entries {
+			if seen[key] {
+				t.Fatalf("Expected %v to be listed once", key)
+			}
+			seen[key] = true
+		}
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+	if len(seen) != 30 {
+		t.Errorf("Expected the 30 matching keys of every peer, got %v", len(seen))
+	}
+}
+
+func TestPeerCache_RebalancesOnMembershipChange(t *testing.T) {
+	peers, servers := startPeers(t, 3)
+	first, second := peers[0], peers[1]
+
+	// Start with the first peer alone so it owns every key.
+	if err := first.SetPeers(nil); err != nil {
+		t.Fatalf("Failed to set peers: %v", err)
+	}
+	for i := 0; i < 30; i++ {
+		first.Set(fmt.Sprintf("key%d", i), "value", time.Minute)
+	}
+
+	if err := first.SetPeers([]string{servers[1].URL}); err != nil {
+		t.Fatalf("Failed to add a peer: %v", err)
+	}
+	second.SetPeers([]string{servers[0].URL})
+
+	moved := second.Stats()["local"].(map[string]interface{})["entries"].(int)
+	kept := first.Stats()["local"].(map[string]interface{})["entries"].(int)
+	if moved == 0 || moved+kept != 30 {
+		t.Fatalf("Expected the keys to be split between both peers, got %d and %d", kept, moved)
+	}
+	for i := 0; i < 30; i++ {
+		if _, err := second.Get(fmt.Sprintf("key%d", i)); err != nil {
+			t.Fatalf("Expected key%d to be reachable after rebalancing: %v", i, err)
+		}
+	}
+}
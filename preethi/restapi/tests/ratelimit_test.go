@@ -0,0 +1,238 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Preethi0716/Cache-Library/preethi/restapi/pkg/api"
+	"github.com/Preethi0716/Cache-Library/preethi/restapi/pkg/cache"
+	"github.com/Preethi0716/Cache-Library/preethi/restapi/pkg/ratelimit"
+)
+
+func TestParseRate(t *testing.T) {
+	for value, want := range map[string]ratelimit.Rate{
+		"":          {},
+		"100/s":     {Requests: 100, Per: time.Second, Burst: 100},
+		"3000/m:50": {Requests: 3000, Per: time.Minute, Burst: 50},
+		"5/10s":     {Requests: 5, Per: 10 * time.Second, Burst: 5},
+	} {
+		if rate, err := ratelimit.ParseRate(value); err != nil || rate != want {
+			t.Errorf("%q: expected %+v, got %+v %v", value, want, rate, err)
+		}
+	}
+	for _, value := range []string{"100", "0/s", "10/day", "10/s:0", "x/s"} {
+		if _, err := ratelimit.ParseRate(value); err == nil {
+			t.Errorf("%q: expected an error", value)
+		}
+	}
+}
+
+func TestLimiter_TokenBucket(t *testing.T) {
+	limiter := ratelimit.NewLimiter(ratelimit.Policy{
+		Reads:  ratelimit.Rate{Requests: 4, Per: time.Second, Burst: 2},
+		Writes: ratelimit.Rate{Requests: 1, Per: time.Second, Burst: 1},
+	}, ratelimit.NewLocalStore(cache.NewLRUCache(10)), nil)
+
+	for i := 0; i < 2; i++ {
+		if rate, _, err := limiter.Allow("client", false); err != nil || !rate.Allowed || rate.Remaining != 1-i {
+			t.Fatalf("Expected read %d within the burst, got %+v %v", i, rate, err)
+		}
+	}
+	rate, _, _ := limiter.Allow("client", false)
+	if rate.Allowed || rate.RetryAfter != time.Second || rate.Limit != 2 {
+		t.Errorf("Expected the third read to wait a second, got %+v", rate)
+	}
+	if rate, _, _ := limiter.Allow("other", false); !rate.Allowed {
+		t.Errorf("Expected another client to have its own bucket, got %+v", rate)
+	}
+	if rate, _, _ := limiter.Allow("client", true); !rate.Allowed {
+		t.Errorf("Expected writes to be limited apart from reads, got %+v", rate)
+	}
+
+	time.Sleep(300 * time.Millisecond)
+	if rate, _, _ := limiter.Allow("client", false); !rate.Allowed {
+		t.Errorf("Expected the bucket to refill, got %+v", rate)
+	}
+}
+
+func TestLimiter_DailyQuota(t *testing.T) {
+	limiter := ratelimit.NewLimiter(ratelimit.Policy{DailyQuota: 3}, ratelimit.NewLocalStore(cache.NewLRUCache(10)), nil)
+	for i := 0; i < 3; i++ {
+		rate, quota, err := limiter.Allow("client", i%2 == 0)
+		if err != nil || rate != nil || !quota.Allowed || quota.Remaining != 2-i {
+			t.Fatalf("Expected request %d within the quota, got %+v %v", i, quota, err)
+		}
+	}
+	_, quota, _ := limiter.Allow("client", false)
+	if quota.Allowed || quota.RetryAfter <= 0 || quota.RetryAfter > 24*time.Hour || quota.RetryAfter != quota.Reset {
+		t.Errorf("Expected the quota to be exhausted until the end of the day, got %+v", quota)
+	}
+}
+
+func TestLimiter_Fallback(t *testing.T) {
+	// Nothing listens on port 1.
+	down := ratelimit.NewRedisStore("localhost:1", 1)
+	defer down.Close()
+	limiter := ratelimit.NewLimiter(ratelimit.Policy{Writes: ratelimit.Rate{Requests: 1, Per: time.Minute, Burst: 1}}, down, ratelimit.NewLocalStore(cache.NewLRUCache(10)))
+	if rate, _, err := limiter.Allow("client", true); err != nil || !rate.Allowed {
+		t.Fatalf("Expected the fallback to allow the first write, got %+v %v", rate, err)
+	}
+	if rate, _, _ := limiter.Allow("client", true); rate.Allowed {
+		t.Errorf("Expected the fallback to keep limiting, got %+v", rate)
+	}
+}
+
+func TestRedisLimiter_Shared(t *testing.T) {
+	policy := ratelimit.Policy{Reads: ratelimit.Rate{Requests: 3, Per: time.Minute, Burst: 3}, DailyQuota: 10}
+	var limiters []*ratelimit.Limiter
+	for i := 0; i < 2; i++ {
+		store := ratelimit.NewRedisStore("localhost:6379", 1)
+		defer store.Close()
+		limiters = append(limiters, ratelimit.NewLimiter(policy, store, nil))
+	}
+	client := fmt.Sprintf("client-%d", time.Now().UnixNano())
+
+	allowed := 0
+	for i := 0; i < 6; i++ {
+		rate, quota, err := limiters[i%2].Allow(client, false)
+		if err != nil {
+			t.Fatalf("Failed to count request: %v", err)
+		}
+		if rate.Allowed {
+			allowed++
+			if quota == nil || quota.Remaining != 10-allowed {
+				t.Errorf("Expected the shared quota to count %d requests, got %+v", allowed, quota)
+			}
+		}
+	}
+	if allowed != 3 {
+		t.Errorf("Expected 3 reads over both instances, got %d", allowed)
+	}
+}
+
+func TestRedisLimiter_Contention(t *testing.T) {
+	store := ratelimit.NewRedisStore("localhost:6379", 1)
+	defer store.Close()
+	// Without a fallback, any request not counted in Redis fails.
+	limiter := ratelimit.NewLimiter(ratelimit.Policy{Writes: ratelimit.Rate{Requests: 10, Per: time.Hour, Burst: 10}, DailyQuota: 1000}, store, nil)
+	client := fmt.Sprintf("client-%d", time.Now().UnixNano())
+
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	allowed, quotaUsed := 0, 0
+	for i := 0; i < 40; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rate, quota, err := limiter.Allow(client, true)
+			mutex.Lock()
+			defer mutex.Unlock()
+			if err != nil {
+				t.Errorf("Expected every request to be counted in Redis, got %v", err)
+				return
+			}
+			if rate.Allowed {
+				allowed++
+				quotaUsed = max(quotaUsed, quota.Limit-quota.Remaining)
+			}
+		}()
+	}
+	wg.Wait()
+	if allowed != 10 || quotaUsed != 10 {
+		t.Errorf("Expected exactly the burst of 10 to pass and be counted, got %d and %d", allowed, quotaUsed)
+	}
+
+	// The limits are kept apart from the cache entries in database 0.
+	c, err := cache.NewRedisCache("localhost:6379")
+	if err != nil {
+		t.Fatalf("Failed to create Redis cache: %v", err)
+	}
+	defer c.Close()
+	if entries, _, err := c.Scan("", "ratelimit:*"+client+"*", 100); err != nil || len(entries) != 0 {
+		t.Errorf("Expected no rate limit keys among the cache entries, got %v %v", entries, err)
+	}
+}
+
+func TestAPI_RateLimit(t *testing.T) {
+	registry := api.NewRegistry()
+	registry.Add("sessions", "lru", cache.NewLRUCache(10))
+	unifiedCache := api.NewUnifiedCache(registry)
+	unifiedCache.Auth = newAuthenticator(t)
+	unifiedCache.Limiter = ratelimit.NewLimiter(ratelimit.Policy{
+		Reads:      ratelimit.Rate{Requests: 2, Per: time.Minute, Burst: 2},
+		Writes:     ratelimit.Rate{Requests: 1, Per: time.Minute, Burst: 1},
+		DailyQuota: 100,
+	}, ratelimit.NewLocalStore(cache.NewLRUCache(10)), nil)
+
+	server := httptest.NewServer(api.NewRouter(unifiedCache))
+	defer server.Close()
+
+	resp := authRequest(t, "POST", server.URL+"/cache/user:1?cache=sessions", "X-API-Key", "web-key", `{"value":"v"}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated || resp.Header.Get("X-RateLimit-Limit") != "1" || resp.Header.Get("X-RateLimit-Remaining") != "0" || resp.Header.Get("X-RateLimit-Quota-Remaining") != "99" {
+		t.Errorf("Expected the write with its limits, got %v %v", resp.StatusCode, resp.Header)
+	}
+	resp = authRequest(t, "POST", server.URL+"/cache/user:2?cache=sessions", "X-API-Key", "web-key", `{"value":"v"}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusTooManyRequests || resp.Header.Get("Retry-After") != "60" {
+		t.Errorf("Expected 429 retrying in 60s, got %v %v", resp.StatusCode, resp.Header)
+	}
+
+	var statuses []int
+	for i := 0; i < 3; i++ {
+		resp := authRequest(t, "GET", server.URL+"/cache/user:1?cache=sessions", "X-API-Key", "web-key", "")
+		resp.Body.Close()
+		statuses = append(statuses, resp.StatusCode)
+	}
+	if statuses[0] != http.StatusOK || statuses[1] != http.StatusOK || statuses[2] != http.StatusTooManyRequests {
+		t.Errorf("Expected two reads and a 429, got %v", statuses)
+	}
+	resp = authRequest(t, "GET", server.URL+"/cache/user:1?cache=sessions", "X-API-Key", "ops-key", "")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected another key to have its own limit, got %v", resp.StatusCode)
+	}
+
+	// Tokens count against the key they were issued under, whatever their subject.
+	resp = authRequest(t, "POST", server.URL+"/auth/tokens", "X-API-Key", "ops-key", `{"subject":"fresh","grants":[{"roles":["read"]}]}`)
+	var issued struct {
+		Token string `json:"token"`
+	}
+	err := json.NewDecoder(resp.Body).Decode(&issued)
+	resp.Body.Close()
+	if err != nil || resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected a token, got %v %v", resp.StatusCode, err)
+	}
+	statuses = nil
+	for i := 0; i < 2; i++ {
+		resp := authRequest(t, "GET", server.URL+"/cache/user:1?cache=sessions", "Authorization", "Bearer "+issued.Token, "")
+		resp.Body.Close()
+		statuses = append(statuses, resp.StatusCode)
+	}
+	if statuses[0] != http.StatusOK || statuses[1] != http.StatusTooManyRequests {
+		t.Errorf("Expected the token to share the reads left to ops-key, got %v", statuses)
+	}
+	// Requests without valid credentials are limited by IP before authenticating.
+	statuses = nil
+	for i := 0; i < 3; i++ {
+		resp := authRequest(t, "GET", server.URL+"/cache/user:1?cache=sessions", "X-API-Key", "guess", "")
+		resp.Body.Close()
+		statuses = append(statuses, resp.StatusCode)
+	}
+	if statuses[0] != http.StatusUnauthorized || statuses[1] != http.StatusUnauthorized || statuses[2] != http.StatusTooManyRequests {
+		t.Errorf("Expected two 401s and a 429, got %v", statuses)
+	}
+
+	for i := 0; i < 5; i++ {
+		resp := authRequest(t, "GET", server.URL+"/healthz", "", "", "")
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || resp.Header.Get("X-RateLimit-Limit") != "" {
+			t.Fatalf("Expected probes not to be limited, got %v", resp.StatusCode)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Preethi0716/Cache-Library/preethi/restapi/config"
+	"github.com/Preethi0716/Cache-Library/preethi/restapi/pkg/api"
+	"github.com/Preethi0716/Cache-Library/preethi/restapi/pkg/cache"
+)
+
+func newTestRegistry(t *testing.T) *api.Registry {
+	registry := api.NewRegistry()
+	registry.RegisterFactory("lru", func(backend config.BackendConfig) (cache.Cache, error) {
+		return cache.NewLRUCache(10), nil
+	})
+	for _, name := range []string{"lru-sessions", "lru-catalog"} {
+		if _, err := registry.Create(config.BackendConfig{Name: name, Type: "lru"}); err != nil {
+			t.Fatalf("Failed to create %v: %v", name, err)
+		}
+	}
+	return registry
+}
+
+func TestRegistry_NamedBackends(t *testing.T) {
+	registry := newTestRegistry(t)
+
+	sessions, _ := registry.Get("lru-sessions")
+	catalog, _ := registry.Get("lru-catalog")
+	if sessions == catalog {
+		t.Fatal("Expected every named backend to get its own cache")
+	}
+
+	if _, err := registry.Create(config.BackendConfig{Name: "lru-sessions", Type: "lru"}); err == nil {
+		t.Error("Expected a duplicate backend name to be rejected")
+	}
+	if _, err := registry.Create(config.BackendConfig{Name: "other", Type: "unknown"}); err == nil || !strings.Contains(err.Error(), "unknown type") {
+		t.Errorf("Expected an unknown type error, got %v", err)
+	}
+
+	backends := registry.Backends()
+	if len(backends) != 2 || backends[0].Name != "lru-catalog" || backends[1].Type != "lru" {
+		t.Errorf("Unexpected backend listing: %v", backends)
+	}
+}
+
+func TestRegistry_ServedByName(t *testing.T) {
+	unifiedCache := api.NewUnifiedCache(newTestRegistry(t))
+	server := httptest.NewServer(api.NewRouter(unifiedCache))
+	defer server.Close()
+
+	resp, err := http.Post(server.URL+"/cache/key1?cache=lru-sessions", "application/json", strings.NewReader(`{"value":"value1"}`))
+	if err != nil || resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Failed to set value: %v %v", resp, err)
+	}
+	resp.Body.Close()
+
+	sessions, _ := unifiedCache.Backends.Get("lru-sessions")
+	if value, err := sessions.Get("key1"); err != nil || value != "value1" {
+		t.Fatalf("Expected value1 in lru-sessions, got %v (error: %v)", value, err)
+	}
+	catalog, _ := unifiedCache.Backends.Get("lru-catalog")
+	if _, err := catalog.Get("key1"); err == nil {
+		t.Fatal("Expected lru-catalog to stay empty")
+	}
+
+	resp, err = http.Get(server.URL + "/cache/key1?cache=missing")
+	if err != nil || resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("Expected 404 for an unknown backend, got %v %v", resp, err)
+	}
+	resp.Body.Close()
+
+	resp, err = http.Get(server.URL + "/backends")
+	if err != nil {
+		t.Fatalf("Failed to list backends: %v", err)
+	}
+	defer resp.Body.Close()
+	var backends []api.BackendInfo
+	if err := json.NewDecoder(resp.Body).Decode(&backends); err != nil || len(backends) != 2 {
+		t.Fatalf("Unexpected backend listing: %v (error: %v)", backends, err)
+	}
+}
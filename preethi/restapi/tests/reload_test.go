@@ -0,0 +1,116 @@
+package tests
+
+import (
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Preethi0716/Cache-Library/preethi/restapi/config"
+	"github.com/Preethi0716/Cache-Library/preethi/restapi/pkg/api"
+)
+
+func TestConfigWatcher_ReloadsChangedFile(t *testing.T) {
+	path := writeConfigFile(t, "cache.conf", "max_lru_size: 5\n")
+	args := []string{"-config", path}
+	cfg, err := config.Load(args)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	var mutex sync.Mutex
+	var applied []int
+	watcher := config.NewWatcher(cfg, args, 10*time.Millisecond, func(cfg *config.CacheConfig) error {
+		mutex.Lock()
+		defer mutex.Unlock()
+		applied = append(applied, cfg.MaxLRUSize)
+		return nil
+	})
+	watcher.Start()
+	defer watcher.Stop()
+
+	// Make sure the modification time moves on filesystems with coarse timestamps.
+	future := time.Now().Add(time.Second)
+	os.WriteFile(path, []byte("max_lru_size: 50\n"), 0o600)
+	os.Chtimes(path, future, future)
+	waitFor(t, func() bool {
+		return watcher.Current().MaxLRUSize == 50
+	})
+
+	// An invalid file is not applied and the previous configuration stays.
+	future = future.Add(time.Second)
+	os.WriteFile(path, []byte("max_lru_size: 0\n"), 0o600)
+	os.Chtimes(path, future, future)
+	if err := watcher.Reload(); err == nil {
+		t.Fatal("Expected an invalid configuration to be rejected")
+	}
+	time.Sleep(50 * time.Millisecond)
+	if watcher.Current().MaxLRUSize != 50 {
+		t.Fatalf("Expected the previous configuration to stay active, got %v", watcher.Current().MaxLRUSize)
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+	if len(applied) != 1 || applied[0] != 50 {
+		t.Fatalf("Expected only the valid configuration to be applied, got %v", applied)
+	}
+}
+
+func TestUnifiedCache_ApplyConfig(t *testing.T) {
+	cfg := config.Default()
+	cfg.MaxLRUSize = 3
+	cfg.Backends = []config.BackendConfig{{Name: "scratch", Type: "lru"}, {Name: "fixed", Type: "lru", Addr: "3"}}
+	unifiedCache, err := api.InitCache(cfg)
+	if err != nil {
+		t.Fatalf("Failed to initialize caches: %v", err)
+	}
+	defer unifiedCache.Close()
+	inMemory, _ := unifiedCache.Backends.Get("inMemory")
+	scratch, _ := unifiedCache.Backends.Get("scratch")
+	fixed, _ := unifiedCache.Backends.Get("fixed")
+	for _, key := range []string{"key1", "key2", "key3"} {
+		inMemory.Set(key, "value", time.Minute)
+		scratch.Set(key, "value", time.Minute)
+		fixed.Set(key, "value", time.Minute)
+	}
+
+	reloaded := *cfg
+	reloaded.MaxLRUSize = 1
+	reloaded.DefaultTTL = 5 * time.Minute
+	reloaded.QueryTimeout = time.Second
+	if err := unifiedCache.ApplyConfig(&reloaded); err != nil {
+		t.Fatalf("Failed to apply config: %v", err)
+	}
+	entries, _ := inMemory.GetAll()
+	if len(entries) != 1 {
+		t.Errorf("Expected the in-memory cache to shrink to 1 entry, got %v", entries)
+	}
+	if entries, _ := scratch.GetAll(); len(entries) != 1 {
+		t.Errorf("Expected a named lru to follow max_lru_size, got %v", entries)
+	}
+	if entries, _ := fixed.GetAll(); len(entries) != 3 {
+		t.Errorf("Expected a named lru with a capacity to keep it, got %v", entries)
+	}
+	if unifiedCache.DefaultTTL() != 5*time.Minute {
+		t.Errorf("Expected the new default TTL, got %v", unifiedCache.DefaultTTL())
+	}
+	if unifiedCache.QueryTimeout() != time.Second {
+		t.Errorf("Expected the new query timeout, got %v", unifiedCache.QueryTimeout())
+	}
+
+	restart := reloaded
+	restart.RedisAddr = "localhost:6380"
+	restart.DefaultTTL = time.Hour
+	if err := unifiedCache.ApplyConfig(&restart); err == nil || !strings.Contains(err.Error(), "redis_addr") {
+		t.Errorf("Expected a redis_addr change to be rejected, got %v", err)
+	}
+	if unifiedCache.DefaultTTL() != 5*time.Minute {
+		t.Errorf("Expected a rejected config to leave the default TTL, got %v", unifiedCache.DefaultTTL())
+	}
+
+	restart = reloaded
+	restart.OptionalBackends = []string{"memcached"}
+	if err := unifiedCache.ApplyConfig(&restart); err == nil || !strings.Contains(err.Error(), "optional_backends") {
+		t.Errorf("Expected an optional_backends change to be rejected, got %v", err)
+	}
+}
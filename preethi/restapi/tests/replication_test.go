@@ -0,0 +1,82 @@
+package tests
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Preethi0716/Cache-Library/preethi/restapi/pkg/cache"
+	"github.com/Preethi0716/Cache-Library/preethi/restapi/pkg/cluster"
+	"github.com/gorilla/mux"
+)
+
+// startReplicatedLeader serves a replication leader from a test server.
+func startReplicatedLeader(t *testing.T) (*cluster.ReplicatedCache, *httptest.Server) {
+	leader := cluster.NewReplicatedLeader(cache.NewLRUCache(100), 100)
+	router := mux.NewRouter()
+	leader.RegisterRoutes(router)
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return leader, server
+}
+
+func startReplicatedFollower(t *testing.T, leaderURL string) *cluster.ReplicatedCache {
+	follower := cluster.NewReplicatedFollower(cache.NewLRUCache(100), leaderURL, 100)
+	follower.Start()
+	// Stop tailing before the leader's server closes, which waits for the stream.
+	t.Cleanup(follower.Promote)
+	return follower
+}
+
+func TestReplicatedCache_FollowerCopiesLeader(t *testing.T) {
+	leader, server := startReplicatedLeader(t)
+	if err := leader.Set("before", "snapshot", time.Minute); err != nil {
+		t.Fatalf("Failed to set on leader: %v", err)
+	}
+
+	follower := startReplicatedFollower(t, server.URL)
+	waitFor(t, func() bool {
+		value, err := follower.Get("before")
+		return err == nil && value == "snapshot"
+	})
+
+	leader.Set("after", "streamed", time.Minute)
+	leader.Delete("before")
+	waitFor(t, func() bool {
+		value, err := follower.Get("after")
+		_, deleteErr := follower.Get("before")
+		return err == nil && value == "streamed" && cache.IsCacheMiss(deleteErr)
+	})
+
+	replication := follower.Stats()["replication"].(map[string]interface{})
+	if replication["role"] != cluster.RoleFollower || replication["seq"] != uint64(3) || replication["lag_ops"] != uint64(0) {
+		t.Errorf("Unexpected follower replication stats: %v", replication)
+	}
+
+	if err := follower.Set("key1", "value1", time.Minute); !errors.Is(err, cluster.ErrReadOnly) {
+		t.Errorf("Expected ErrReadOnly from a follower, got %v", err)
+	}
+}
+
+func TestReplicatedCache_PromoteFollower(t *testing.T) {
+	leader, server := startReplicatedLeader(t)
+	leader.Set("key1", "value1", time.Minute)
+
+	follower := startReplicatedFollower(t, server.URL)
+	waitFor(t, func() bool {
+		_, err := follower.Get("key1")
+		return err == nil
+	})
+
+	follower.Promote()
+	if follower.Role() != cluster.RoleLeader {
+		t.Fatalf("Expected the promoted follower to lead, got %v", follower.Role())
+	}
+	if err := follower.Set("key2", "value2", time.Minute); err != nil {
+		t.Fatalf("Failed to set on promoted follower: %v", err)
+	}
+	if seq := follower.Stats()["replication"].(map[string]interface{})["seq"]; seq != uint64(2) {
+		t.Errorf("Expected the promoted follower to continue at seq 2, got %v", seq)
+	}
+}
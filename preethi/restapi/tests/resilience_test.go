@@ -0,0 +1,128 @@
+package tests
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Preethi0716/Cache-Library/preethi/restapi/pkg/cache"
+)
+
+// flakyCache fails the next failures calls with a network error.
+type flakyCache struct {
+	*cache.LRUCache
+	mutex    sync.Mutex
+	failures int
+	calls    int
+}
+
+func (c *flakyCache) Get(key string) (interface{}, error) {
+	c.mutex.Lock()
+	c.calls++
+	if c.failures > 0 {
+		c.failures--
+		c.mutex.Unlock()
+		return nil, &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	}
+	c.mutex.Unlock()
+	return c.LRUCache.Get(key)
+}
+
+func testPolicy() cache.ResiliencePolicy {
+	policy := cache.DefaultResiliencePolicy()
+	policy.MinRequests = 4
+	policy.OpenTimeout = 50 * time.Millisecond
+	policy.HalfOpenProbes = 1
+	policy.BaseBackoff = time.Millisecond
+	policy.MaxBackoff = 5 * time.Millisecond
+	return policy
+}
+
+func TestResilientCache_RetriesFailures(t *testing.T) {
+	flaky := &flakyCache{LRUCache: cache.NewLRUCache(10), failures: 2}
+	flaky.Set("key1", "value1", time.Minute)
+	c := cache.NewResilientCache("flaky", flaky, testPolicy())
+
+	if value, err := c.Get("key1"); err != nil || value != "value1" {
+		t.Fatalf("Expected value1 after retries, got %v (error: %v)", value, err)
+	}
+	if retries := c.Stats()["retries"]; retries != 2 {
+		t.Errorf("Expected 2 retries, got %v", retries)
+	}
+
+	// A miss is an answer, not a failure.
+	if _, err := c.Get("missing"); !cache.IsCacheMiss(err) {
+		t.Fatalf("Expected a cache miss, got %v", err)
+	}
+	if flaky.calls != 4 {
+		t.Errorf("Expected a miss not to be retried, got %d calls", flaky.calls)
+	}
+}
+
+func TestResilientCache_BreakerOpensAndRecovers(t *testing.T) {
+	flaky := &flakyCache{LRUCache: cache.NewLRUCache(10), failures: 100}
+	flaky.Set("key1", "value1", time.Minute)
+	policy := testPolicy()
+	policy.Retries = 0
+	c := cache.NewResilientCache("flaky", flaky, policy)
+
+	for i := 0; i < 4; i++ {
+		c.Get("key1")
+	}
+	if c.BreakerState() != cache.BreakerOpen {
+		t.Fatalf("Expected the breaker to open, got %v", c.BreakerState())
+	}
+
+	calls := flaky.calls
+	_, err := c.Get("key1")
+	var unavailable *cache.UnavailableError
+	if !errors.As(err, &unavailable) || !errors.Is(err, cache.ErrCircuitOpen) || unavailable.RetryAfter <= 0 {
+		t.Fatalf("Expected an open circuit error with a retry delay, got %v", err)
+	}
+	if flaky.calls != calls {
+		t.Fatal("Expected an open breaker not to call the backend")
+	}
+
+	// After the open timeout one successful probe closes the breaker.
+	flaky.mutex.Lock()
+	flaky.failures = 0
+	flaky.mutex.Unlock()
+	time.Sleep(policy.OpenTimeout)
+	if value, err := c.Get("key1"); err != nil || value != "value1" {
+		t.Fatalf("Expected the half-open probe to succeed, got %v (error: %v)", value, err)
+	}
+	if c.BreakerState() != cache.BreakerClosed {
+		t.Fatalf("Expected the breaker to close, got %v", c.BreakerState())
+	}
+}
+
+// lostReplyCache applies every update but reports a network error, as if
+// the answer was lost on the way back.
+type lostReplyCache struct {
+	*cache.LRUCache
+}
+
+func (c lostReplyCache) Update(key string, fn cache.UpdateFunc) error {
+	if err := c.LRUCache.Update(key, fn); err != nil {
+		return err
+	}
+	return &net.OpError{Op: "read", Net: "tcp", Err: errors.New("connection reset")}
+}
+
+func TestResilientCache_UpdateNotRetried(t *testing.T) {
+	lost := lostReplyCache{cache.NewLRUCache(10)}
+	lost.Set("counter", 0, time.Minute)
+	c := cache.NewResilientCache("lost", lost, testPolicy())
+
+	err := c.Update("counter", func(current interface{}) (interface{}, time.Duration, error) {
+		return current.(int) + 1, time.Minute, nil
+	})
+	if err == nil {
+		t.Fatal("Expected the lost reply to be reported")
+	}
+	if value, _ := lost.Get("counter"); value != 1 {
+		t.Errorf("Expected the update to be applied once, got %v", value)
+	}
+}
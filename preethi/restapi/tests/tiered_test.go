@@ -0,0 +1,86 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Preethi0716/Cache-Library/preethi/restapi/pkg/cache"
+)
+
+func TestTieredCache_WritesThroughBothTiers(t *testing.T) {
+	l1, l2 := cache.NewLRUCache(2), cache.NewLRUCache(10)
+	c := cache.NewTieredCache(l1, l2, time.Minute)
+
+	if err := c.Set("key1", "value1", time.Hour); err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+	for name, tier := range map[string]cache.Cache{"l1": l1, "l2": l2} {
+		if value, err := tier.Get("key1"); err != nil || value != "value1" {
+			t.Fatalf("Expected value1 in %v, got %v", name, value)
+		}
+	}
+
+	if err := c.Delete("key1"); err != nil {
+		t.Fatalf("Failed to delete key: %v", err)
+	}
+	if _, err := l2.Get("key1"); err == nil {
+		t.Fatal("Expected key1 to be deleted from l2")
+	}
+	if _, err := l1.Get("key1"); err == nil {
+		t.Fatal("Expected key1 to be deleted from l1")
+	}
+	if err := c.Delete("key1"); !cache.IsCacheMiss(err) {
+		t.Fatalf("Expected a cache miss deleting a missing key, got %v", err)
+	}
+}
+
+func TestTieredCache_PromotesL2Hits(t *testing.T) {
+	l1, l2 := cache.NewLRUCache(2), cache.NewLRUCache(10)
+	c := cache.NewTieredCache(l1, l2, 20*time.Millisecond)
+
+	l2.Set("key1", "value1", time.Hour)
+	if value, err := c.Get("key1"); err != nil || value != "value1" {
+		t.Fatalf("Expected value1 from l2, got %v", value)
+	}
+	if value, err := l1.Get("key1"); err != nil || value != "value1" {
+		t.Fatalf("Expected value1 to be promoted to l1, got %v", value)
+	}
+
+	// Promoted entries expire from l1 with the shorter TTL.
+	time.Sleep(40 * time.Millisecond)
+	if _, err := l1.Get("key1"); err == nil {
+		t.Fatal("Expected the promoted entry to expire from l1")
+	}
+
+	stats := c.Stats()
+	if stats["l2_hits"].(int64) != 1 {
+		t.Fatalf("Expected one l2 hit, got %v", stats["l2_hits"])
+	}
+}
+
+func TestTieredCache_PromotesWithRemainingTTL(t *testing.T) {
+	l1, l2 := cache.NewLRUCache(2), cache.NewLRUCache(10)
+	c := cache.NewTieredCache(l1, l2, time.Hour)
+
+	// Promoted entries never outlive their L2 copy.
+	l2.Set("key1", "value1", 30*time.Millisecond)
+	if value, err := c.Get("key1"); err != nil || value != "value1" {
+		t.Fatalf("Expected value1 from l2, got %v", value)
+	}
+	if _, expiresAt, err := l1.GetWithExpiration("key1"); err != nil || expiresAt.IsZero() || time.Until(expiresAt) > 30*time.Millisecond {
+		t.Fatalf("Expected the promoted entry to expire with l2, got %v %v", expiresAt, err)
+	}
+	l2.Set("key2", "value2", 0)
+	c.Get("key2")
+	if _, expiresAt, err := l1.GetWithExpiration("key2"); err != nil || time.Until(expiresAt) <= 59*time.Minute {
+		t.Fatalf("Expected an entry without expiry to be promoted for l1TTL, got %v %v", expiresAt, err)
+	}
+}
+
+func TestTieredCache_Miss(t *testing.T) {
+	c := cache.NewTieredCache(cache.NewLRUCache(2), cache.NewLRUCache(10), time.Minute)
+
+	if _, err := c.Get("missing"); !cache.IsCacheMiss(err) {
+		t.Fatalf("Expected a cache miss, got %v", err)
+	}
+}
@@ -0,0 +1,133 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Preethi0716/Cache-Library/preethi/restapi/config"
+	"github.com/Preethi0716/Cache-Library/preethi/restapi/pkg/api"
+	"github.com/Preethi0716/Cache-Library/preethi/restapi/pkg/cache"
+)
+
+type writeResponse struct {
+	Key       string     `json:"key"`
+	TTL       string     `json:"ttl"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+func newTTLServer(t *testing.T) (*httptest.Server, *api.Registry) {
+	registry := api.NewRegistry()
+	// An LRU standing in for memcached, to check its ttl limit.
+	registry.RegisterFactory("memcached", func(backend config.BackendConfig) (cache.Cache, error) {
+		return cache.NewLRUCache(10), nil
+	})
+	registry.Add("inMemory", "lru", cache.NewLRUCache(10))
+	if _, err := registry.Create(config.BackendConfig{Name: "memcached", Type: "memcached"}); err != nil {
+		t.Fatalf("Failed to create backend: %v", err)
+	}
+	return httptest.NewServer(api.NewRouter(api.NewUnifiedCache(registry))), registry
+}
+
+func postValue(t *testing.T, url string, body string) (int, writeResponse) {
+	resp, err := http.Post(url, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("Failed to post: %v", err)
+	}
+	defer resp.Body.Close()
+	var response writeResponse
+	if resp.StatusCode == http.StatusCreated {
+		if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+	}
+	return resp.StatusCode, response
+}
+
+func TestLRUCache_NoExpiry(t *testing.T) {
+	c := cache.NewLRUCache(2)
+	c.Set("key1", "value1", 0)
+	time.Sleep(10 * time.Millisecond)
+	if value, err := c.Get("key1"); err != nil || value != "value1" {
+		t.Fatalf("Expected a zero ttl to never expire, got %v, %v", value, err)
+	}
+	if _, expiration, _ := c.GetWithExpiration("key1"); !expiration.IsZero() {
+		t.Errorf("Expected no expiration, got %v", expiration)
+	}
+}
+
+func TestMemcachedCache_MaxTTL(t *testing.T) {
+	c, err := cache.NewMemcachedCache("localhost:11211")
+	if err != nil {
+		t.Fatalf("Failed to create memcached cache: %v", err)
+	}
+	if err := c.Set("key1", "value1", cache.MemcachedMaxTTL+time.Hour); err == nil {
+		t.Fatal("Expected a ttl beyond 30 days to be rejected")
+	}
+}
+
+func TestAPI_TTL(t *testing.T) {
+	server, registry := newTTLServer(t)
+	defer server.Close()
+
+	status, response := postValue(t, server.URL+"/cache/key1?cache=inMemory", `{"value":"value1","ttl":"90s"}`)
+	if status != http.StatusCreated || response.TTL != "1m30s" || response.ExpiresAt == nil {
+		t.Fatalf("Expected the ttl to be echoed, got %v %+v", status, response)
+	}
+	if remaining := time.Until(*response.ExpiresAt); remaining < 80*time.Second || remaining > 90*time.Second {
+		t.Errorf("Expected expires_at about 90s ahead, got %v", remaining)
+	}
+
+	status, response = postValue(t, server.URL+"/cache/key2?cache=inMemory&ttl=30", `{"value":"value2"}`)
+	if status != http.StatusCreated || response.TTL != "30s" {
+		t.Errorf("Expected the ttl query parameter in seconds, got %v %+v", status, response)
+	}
+
+	status, response = postValue(t, server.URL+"/cache/key3?cache=inMemory", `{"value":"value3"}`)
+	if status != http.StatusCreated || response.TTL != "1m0s" {
+		t.Errorf("Expected the default ttl, got %v %+v", status, response)
+	}
+
+	status, response = postValue(t, server.URL+"/cache/key4?cache=inMemory", `{"value":"value4","ttl":"never"}`)
+	if status != http.StatusCreated || response.TTL != "never" || response.ExpiresAt != nil {
+		t.Errorf("Expected no expiry, got %v %+v", status, response)
+	}
+	inMemory, _ := registry.Get("inMemory")
+	if _, expiration, _ := inMemory.(*cache.LRUCache).GetWithExpiration("key4"); !expiration.IsZero() {
+		t.Errorf("Expected key4 to be stored without expiry, got %v", expiration)
+	}
+
+	expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	status, response = postValue(t, server.URL+"/cache/key5?cache=inMemory", `{"value":"value5","expires_at":"`+expiresAt.Format(time.RFC3339)+`"}`)
+	if status != http.StatusCreated || response.ExpiresAt == nil || !response.ExpiresAt.Equal(expiresAt) {
+		t.Errorf("Expected expires_at %v to be echoed, got %v %+v", expiresAt, status, response)
+	}
+}
+
+func TestAPI_TTLRejected(t *testing.T) {
+	server, _ := newTTLServer(t)
+	defer server.Close()
+
+	past := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	for name, request := range map[string]string{
+		"unparseable ttl":      `{"value":"value1","ttl":"soon"}`,
+		"negative ttl":         `{"value":"value1","ttl":"-5s"}`,
+		"ttl and expires_at":   `{"value":"value1","ttl":"5s","expires_at":"` + past + `"}`,
+		"past expires_at":      `{"value":"value1","expires_at":"` + past + `"}`,
+		"unparseable deadline": `{"value":"value1","expires_at":"tomorrow"}`,
+	} {
+		if status, _ := postValue(t, server.URL+"/cache/key1?cache=inMemory", request); status != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %v", name, status)
+		}
+	}
+
+	if status, _ := postValue(t, server.URL+"/cache/key1?cache=memcached", `{"value":"value1","ttl":"800h"}`); status != http.StatusBadRequest {
+		t.Errorf("Expected a ttl beyond the memcached limit to be rejected, got %v", status)
+	}
+	if status, _ := postValue(t, server.URL+"/cache/key1?cache=memcached", `{"value":"value1","ttl":"700h"}`); status != http.StatusCreated {
+		t.Errorf("Expected a ttl within the memcached limit to be accepted, got %v", status)
+	}
+}
@@ -0,0 +1,90 @@
+package tests
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Preethi0716/Cache-Library/preethi/restapi/pkg/api"
+	"github.com/Preethi0716/Cache-Library/preethi/restapi/pkg/cache"
+)
+
+// newLRUServer serves the API over a single in-memory cache, which is
+// returned to check what was stored.
+func newLRUServer() (*httptest.Server, cache.Cache) {
+	registry := api.NewRegistry()
+	lru := cache.NewLRUCache(10)
+	registry.Add("inMemory", "lru", lru)
+	return httptest.NewServer(api.NewRouter(api.NewUnifiedCache(registry))), lru
+}
+
+// send makes a request to the API and returns the response with its body.
+func send(t *testing.T, method, url, contentType, body string, header map[string]string) (*http.Response, string) {
+	req, _ := http.NewRequest(method, url, strings.NewReader(body))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to %s: %v", method, err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	return resp, string(data)
+}
+
+func TestAPI_Values(t *testing.T) {
+	server, _ := newLRUServer()
+	defer server.Close()
+
+	for key, test := range map[string]struct {
+		contentType string
+		body        string
+		wantType    string
+		want        string
+	}{
+		"string": {"application/json", `{"value":"value1"}`, "text/plain; charset=utf-8", "value1"},
+		"object": {"application/json", `{"value": {"name": "value1", "tags": [1, 2]}}`, "application/json", `{"name":"value1","tags":[1,2]}`},
+		"number": {"application/json; charset=utf-8", `{"value": 42}`, "application/json", `42`},
+		"binary": {"image/png", "\x89PNG\x00\x01", "image/png", "\x89PNG\x00\x01"},
+		"text":   {"text/plain", "plain text", "text/plain; charset=utf-8", "plain text"},
+	} {
+		resp, _ := send(t, "POST", server.URL+"/cache/"+key+"?cache=inMemory", test.contentType, test.body, nil)
+		if resp.StatusCode != http.StatusCreated {
+			t.Fatalf("%s: expected 201, got %v", key, resp.StatusCode)
+		}
+		resp, body := send(t, "GET", server.URL+"/cache/"+key+"?cache=inMemory", "", "", nil)
+		if contentType := resp.Header.Get("Content-Type"); resp.StatusCode != http.StatusOK || contentType != test.wantType || body != test.want {
+			t.Errorf("%s: expected %q as %v, got %v %q as %v", key, test.want, test.wantType, resp.StatusCode, body, contentType)
+		}
+	}
+
+	_, body := send(t, "GET", server.URL+"/cache", "", "", nil)
+	if !strings.Contains(body, `"key":"object","value":{"name":"value1","tags":[1,2]}`) || !strings.Contains(body, `"content_type":"image/png"`) {
+		t.Errorf("Expected JSON documents inline and binary values with their content type, got %s", body)
+	}
+}
+
+func TestAPI_ValuesRejected(t *testing.T) {
+	server, _ := newLRUServer()
+	defer server.Close()
+
+	for name, body := range map[string]string{
+		"missing value": `{"ttl":"5s"}`,
+		"null value":    `{"value":null}`,
+		"not JSON":      `value1`,
+	} {
+		if resp, _ := send(t, "POST", server.URL+"/cache/key1?cache=inMemory", "application/json", body, nil); resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %v", name, resp.StatusCode)
+		}
+	}
+
+	if resp, _ := send(t, "POST", server.URL+"/cache/key1?cache=inMemory", "application/octet-stream", string(make([]byte, 2<<20)), nil); resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected 413 for a large value, got %v", resp.StatusCode)
+	}
+}
@@ -0,0 +1,155 @@
+package tests
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Preethi0716/Cache-Library/preethi/restapi/pkg/cache"
+)
+
+// testConcurrentUpdates increments a counter from many goroutines; none of
+// the increments may be lost.
+func testConcurrentUpdates(t *testing.T, c cache.Cache, key string) {
+	c.Delete(key)
+	if err := cache.Add(c, key, "0", time.Minute); err != nil {
+		t.Fatalf("Failed to add %v: %v", key, err)
+	}
+	if err := cache.Add(c, key, "0", time.Minute); !errors.Is(err, cache.ErrKeyExists) {
+		t.Fatalf("Expected ErrKeyExists, got %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := cache.Update(c, key, func(current interface{}) (interface{}, time.Duration, error) {
+				var n int
+				fmt.Sscan(current.(string), &n)
+				return fmt.Sprint(n + 1), time.Minute, nil
+			})
+			if err != nil && !errors.Is(err, cache.ErrUpdateConflict) {
+				t.Errorf("Failed to update %v: %v", key, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	value, err := c.Get(key)
+	if err != nil {
+		t.Fatalf("Failed to get %v: %v", key, err)
+	}
+	// Updates that gave up after repeated conflicts are reported, not lost.
+	var n int
+	fmt.Sscan(value.(string), &n)
+	if n == 0 || n > 20 {
+		t.Errorf("Expected up to 20 increments, got %v", value)
+	}
+	if err := cache.Update(c, "missing:"+key, nil); !cache.IsCacheMiss(err) {
+		t.Errorf("Expected a cache miss updating a missing key, got %v", err)
+	}
+}
+
+func TestLRUCache_AddUpdate(t *testing.T) {
+	c := cache.NewLRUCache(10)
+	testConcurrentUpdates(t, c, "counter")
+	if value, _ := c.Get("counter"); value != "20" {
+		t.Errorf("Expected all 20 increments under the lock, got %v", value)
+	}
+}
+
+func TestRedisCache_AddUpdate(t *testing.T) {
+	c, err := cache.NewRedisCache("localhost:6379")
+	if err != nil {
+		t.Fatalf("Failed to create Redis cache: %v", err)
+	}
+	testConcurrentUpdates(t, c, "counter")
+}
+
+func TestMemcachedCache_AddUpdate(t *testing.T) {
+	c, err := cache.NewMemcachedCache("localhost:11211")
+	if err != nil {
+		t.Fatalf("Failed to create memcached cache: %v", err)
+	}
+	testConcurrentUpdates(t, c, "counter")
+}
+
+func TestAPI_CreateAndReplace(t *testing.T) {
+	server, _ := newLRUServer()
+	defer server.Close()
+	url := server.URL + "/cache/key1?cache=inMemory"
+
+	resp, _ := send(t, "POST", url, "application/json", `{"value":"value1"}`, nil)
+	if resp.StatusCode != http.StatusCreated || resp.Header.Get("Location") != "/cache/key1?cache=inMemory" {
+		t.Fatalf("Expected 201 with a Location, got %v %v", resp.StatusCode, resp.Header.Get("Location"))
+	}
+	etag := resp.Header.Get("ETag")
+	if resp, _ := send(t, "POST", url, "application/json", `{"value":"value2"}`, nil); resp.StatusCode != http.StatusConflict {
+		t.Errorf("Expected 409 creating an existing key, got %v", resp.StatusCode)
+	}
+	if resp, _ := send(t, "PUT", url, "application/json", `{"value":"value2"}`, map[string]string{"If-None-Match": "*"}); resp.StatusCode != http.StatusConflict {
+		t.Errorf("Expected 409 for PUT with If-None-Match: *, got %v", resp.StatusCode)
+	}
+	if resp, _ := send(t, "PUT", url, "application/json", `{"value":"value2"}`, map[string]string{"If-Match": `"stale"`}); resp.StatusCode != http.StatusPreconditionFailed {
+		t.Errorf("Expected 412 for a stale If-Match, got %v", resp.StatusCode)
+	}
+	if resp, _ := send(t, "PUT", url, "application/json", `{"value":"value2"}`, map[string]string{"If-Match": etag}); resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected 200 for a matching If-Match, got %v", resp.StatusCode)
+	}
+	if resp, _ := send(t, "PUT", url, "application/json", `{"value":"value3"}`, nil); resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected 200 replacing a key, got %v", resp.StatusCode)
+	}
+	if _, body := send(t, "GET", url, "", "", nil); body != "value3" {
+		t.Errorf("Expected value3, got %q", body)
+	}
+
+	missing := server.URL + "/cache/key2?cache=inMemory"
+	if resp, _ := send(t, "PUT", missing, "application/json", `{"value":"value1"}`, map[string]string{"If-Match": "*"}); resp.StatusCode != http.StatusPreconditionFailed {
+		t.Errorf("Expected 412 for If-Match on a missing key, got %v", resp.StatusCode)
+	}
+	if resp, _ := send(t, "PUT", missing, "application/json", `{"value":"value1"}`, nil); resp.StatusCode != http.StatusCreated {
+		t.Errorf("Expected 201 for PUT creating a key, got %v", resp.StatusCode)
+	}
+	if resp, _ := send(t, "DELETE", missing, "", "", nil); resp.StatusCode != http.StatusNoContent {
+		t.Errorf("Expected 204 deleting a key, got %v", resp.StatusCode)
+	}
+}
+
+func TestAPI_Patch(t *testing.T) {
+	server, _ := newLRUServer()
+	defer server.Close()
+	url := server.URL + "/cache/doc1?cache=inMemory"
+
+	send(t, "POST", url, "application/json", `{"value":{"a":1,"b":{"c":2,"d":3},"big":12345678901234567890},"ttl":"1h"}`, nil)
+	resp, _ := send(t, "PATCH", url, "application/merge-patch+json", `{"b":{"c":null,"e":[4]},"f":"x"}`, nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200 for a patch, got %v", resp.StatusCode)
+	}
+	resp, body := send(t, "GET", url, "", "", nil)
+	if body != `{"a":1,"b":{"d":3,"e":[4]},"big":12345678901234567890,"f":"x"}` {
+		t.Errorf("Unexpected patched document %s", body)
+	}
+	if cacheControl := resp.Header.Get("Cache-Control"); cacheControl != "max-age=3599" && cacheControl != "max-age=3600" {
+		t.Errorf("Expected the patch to keep the expiry, got %q", cacheControl)
+	}
+
+	if resp, _ := send(t, "PATCH", url, "application/merge-patch+json", `{"a":2}`, map[string]string{"If-Match": `"stale"`}); resp.StatusCode != http.StatusPreconditionFailed {
+		t.Errorf("Expected 412 for a stale If-Match, got %v", resp.StatusCode)
+	}
+	if resp, _ := send(t, "PATCH", url, "text/plain", `{"a":2}`, nil); resp.StatusCode != http.StatusUnsupportedMediaType {
+		t.Errorf("Expected 415 for a patch that is not JSON, got %v", resp.StatusCode)
+	}
+	if resp, _ := send(t, "PATCH", server.URL+"/cache/missing?cache=inMemory", "application/merge-patch+json", `{"a":2}`, nil); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 patching a missing key, got %v", resp.StatusCode)
+	}
+
+	text := server.URL + "/cache/text1?cache=inMemory"
+	send(t, "POST", text, "application/json", `{"value":"value1"}`, nil)
+	if resp, _ := send(t, "PATCH", text, "application/merge-patch+json", `{"a":2}`, nil); resp.StatusCode != http.StatusConflict {
+		t.Errorf("Expected 409 patching a text value, got %v", resp.StatusCode)
+	}
+}